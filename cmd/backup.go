@@ -0,0 +1,433 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultBackupWaitTimeout = 60 * time.Minute
+	hiddenSecretValue        = "********"
+)
+
+var (
+	// backupTargetAccessKeyNames lists the keys under which the access key ID is searched for in the credentials secret
+	backupTargetAccessKeyNames = []string{"AWS_ACCESS_KEY_ID", "accessKeyId"}
+	// backupTargetSecretKeyNames lists the keys under which the secret access key is searched for in the credentials secret
+	backupTargetSecretKeyNames = []string{"AWS_SECRET_ACCESS_KEY", "secretAccessKey"}
+)
+
+// BackupTargetData is a Data Structure that holds the backup target configuration to display
+type BackupTargetData struct {
+	Type               string   `yaml:"type"`
+	Endpoint           string   `yaml:"endpoint"`
+	BucketName         string   `yaml:"bucketName,omitempty"`
+	BucketRegion       string   `yaml:"bucketRegion,omitempty"`
+	AccessKeyID        string   `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey    string   `yaml:"secretAccessKey,omitempty"`
+	VirtualHostedStyle bool     `yaml:"virtualHostedStyle,omitempty"`
+	Conditions         []string `yaml:"conditions,omitempty"`
+}
+
+// vmBackupCommand defines the CLI sub-command `vm backup` that manages VM backups on the backup target
+func vmBackupCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "backup",
+		Usage:  "Manage VM backups on the backup target",
+		Action: vmBackupList,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&outputFlag,
+			&noHeadersFlag,
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:        "create",
+				Aliases:     []string{"c"},
+				Usage:       "Create a backup of a VM",
+				Description: "\nCreates a backup of the VM given as an argument on the configured backup target and waits until it is ready to use",
+				ArgsUsage:   "VM_NAME",
+				Action:      vmBackupCreate,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "Name of the backup, defaults to the VM name followed by a timestamp",
+					},
+					&cli.BoolFlag{
+						Name:  "no-wait",
+						Usage: "Do not wait for the backup to be ready to use",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Maximum duration to wait for the backup to be ready to use",
+						Value: defaultBackupWaitTimeout,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Aliases:     []string{"ls"},
+				Usage:       "List VM backups",
+				Description: "\nLists the VM backups, optionally only the ones of the VM given as an argument",
+				ArgsUsage:   "[VM_NAME]",
+				Action:      vmBackupList,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				},
+			},
+			{
+				Name: "delete",
+				Aliases: []string{
+					"del",
+					"rm",
+				},
+				Usage:     "Delete VM backups",
+				ArgsUsage: "[BACKUP_NAME...]",
+				Action:    vmBackupDelete,
+				Flags: []cli.Flag{
+					&nsFlag,
+				},
+			},
+			{
+				Name:        "restore",
+				Usage:       "Restore a VM backup",
+				Description: "\nRestores a VM backup either to a new VM (--new-vm) or by replacing the VM it was taken from (--replace), which must be stopped",
+				ArgsUsage:   "BACKUP_NAME",
+				Action:      vmBackupRestore,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:  "new-vm",
+						Usage: "Name of the new VM to create from the backup",
+					},
+					&cli.BoolFlag{
+						Name:  "replace",
+						Usage: "Replace the VM the backup was taken from",
+					},
+					&cli.BoolFlag{
+						Name:  "delete-volumes",
+						Usage: "When replacing a VM, delete its previous volumes instead of retaining them",
+					},
+					&cli.BoolFlag{
+						Name:  "no-wait",
+						Usage: "Do not wait for the restore to complete",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Maximum duration to wait for the restore to complete",
+						Value: defaultBackupWaitTimeout,
+					},
+				},
+			},
+		},
+	}
+}
+
+// BackupTargetCommand defines the CLI command that manages the backup target of Harvester
+func BackupTargetCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "backup-target",
+		Usage:  "Manage the backup target of Harvester",
+		Action: backupTargetShow,
+		Subcommands: []*cli.Command{
+			{
+				Name:        "show",
+				Aliases:     []string{"get"},
+				Usage:       "Show the backup target",
+				Description: "\nShows the backup target configured in Harvester, secrets are hidden",
+				Action:      backupTargetShow,
+			},
+			{
+				Name:        "set",
+				Usage:       "Set the backup target",
+				Description: "\nConfigures an NFS or S3 backup target in Harvester, S3 credentials are read from a Kubernetes secret",
+				Action:      backupTargetSet,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "type",
+						Usage:    "Type of the backup target, either nfs or s3",
+						EnvVars:  []string{"HARVESTER_BACKUP_TARGET_TYPE"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "endpoint",
+						Usage:    "Endpoint of the backup target, e.g. nfs://server:/path or https://s3.example.com",
+						EnvVars:  []string{"HARVESTER_BACKUP_TARGET_ENDPOINT"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "bucket-name",
+						Usage:   "Name of the S3 bucket",
+						EnvVars: []string{"HARVESTER_BACKUP_TARGET_BUCKET_NAME"},
+					},
+					&cli.StringFlag{
+						Name:    "bucket-region",
+						Usage:   "Region of the S3 bucket",
+						EnvVars: []string{"HARVESTER_BACKUP_TARGET_BUCKET_REGION"},
+					},
+					&cli.StringFlag{
+						Name:    "credentials-secret",
+						Usage:   "Secret holding the S3 credentials in the format <namespace>/<secret-name>, with the keys AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+						EnvVars: []string{"HARVESTER_BACKUP_TARGET_CREDENTIALS_SECRET"},
+					},
+					&cli.StringFlag{
+						Name:    "cert-file",
+						Usage:   "Path to the CA certificate of the S3 endpoint",
+						EnvVars: []string{"HARVESTER_BACKUP_TARGET_CERT_FILE"},
+					},
+					&cli.BoolFlag{
+						Name:  "virtual-hosted-style",
+						Usage: "Use virtual hosted style requests for S3",
+					},
+				},
+			},
+		},
+	}
+}
+
+// vmBackupCreate implements the `vm backup create` command, it checks that a backup target is configured before creating the backup
+func vmBackupCreate(ctx *cli.Context) error {
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, _, err := getBackupTarget(c)
+	if err != nil {
+		return err
+	}
+
+	if target.IsDefaultBackupTarget() || target.Endpoint == "" {
+		return fmt.Errorf("no backup target is configured in Harvester, please set one using `harvester backup-target set`")
+	}
+
+	return vmBackupCreateWithType(ctx, v1beta1.Backup)
+}
+
+// vmBackupList implements the `vm backup list` command
+func vmBackupList(ctx *cli.Context) error {
+	return vmBackupListWithType(ctx, v1beta1.Backup)
+}
+
+// vmBackupDelete implements the `vm backup delete` command
+func vmBackupDelete(ctx *cli.Context) error {
+	return vmBackupDeleteWithType(ctx, v1beta1.Backup)
+}
+
+// vmBackupRestore implements the `vm backup restore` command
+func vmBackupRestore(ctx *cli.Context) error {
+	if ctx.Bool("delete-volumes") && !ctx.Bool("replace") {
+		return fmt.Errorf("the flag --delete-volumes can only be used together with --replace")
+	}
+
+	return vmBackupRestoreWithType(ctx, v1beta1.Backup)
+}
+
+// getBackupTarget reads and decodes the backup-target setting of Harvester
+func getBackupTarget(c *harvclient.Clientset) (*settings.BackupTarget, *v1beta1.Setting, error) {
+	setting, err := c.HarvesterhciV1beta1().Settings().Get(context.TODO(), settings.BackupTargetSettingName, k8smetav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("encountered issue when querying Harvester for setting %s: %w", settings.BackupTargetSettingName, err)
+	}
+
+	value := setting.Value
+	if value == "" {
+		value = setting.Default
+	}
+
+	target, err := settings.DecodeBackupTarget(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encountered issue when decoding setting %s: %w", settings.BackupTargetSettingName, err)
+	}
+
+	return target, setting, nil
+}
+
+// backupTargetShow implements the `backup-target show` command, it prints the backup target in YAML format, hiding the secret access key
+func backupTargetShow(ctx *cli.Context) error {
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, setting, err := getBackupTarget(c)
+	if err != nil {
+		return err
+	}
+
+	toShow := buildBackupTargetData(target, setting)
+
+	targetYAMLbytes, err := yaml.Marshal(&toShow)
+	if err != nil {
+		return fmt.Errorf("failed during encoding an object to YAML: %w", err)
+	}
+
+	fmt.Println(string(targetYAMLbytes))
+	return nil
+}
+
+// buildBackupTargetData maps the backup target and the conditions of its setting to their display structure, hiding the secret access key
+func buildBackupTargetData(target *settings.BackupTarget, setting *v1beta1.Setting) BackupTargetData {
+	toShow := BackupTargetData{
+		Type:               string(target.Type),
+		Endpoint:           target.Endpoint,
+		BucketName:         target.BucketName,
+		BucketRegion:       target.BucketRegion,
+		AccessKeyID:        target.AccessKeyID,
+		VirtualHostedStyle: target.VirtualHostedStyle,
+	}
+
+	if target.SecretAccessKey != "" {
+		toShow.SecretAccessKey = hiddenSecretValue
+	}
+
+	for _, condition := range setting.Status.Conditions {
+		toShow.Conditions = append(toShow.Conditions, strings.TrimSpace(fmt.Sprintf("%s=%s %s", condition.Type, condition.Status, condition.Message)))
+	}
+
+	return toShow
+}
+
+// backupTargetSet implements the `backup-target set` command, it builds the backup target from the flags and stores it in the backup-target setting
+func backupTargetSet(ctx *cli.Context) error {
+	target := &settings.BackupTarget{
+		Type:               settings.TargetType(ctx.String("type")),
+		Endpoint:           ctx.String("endpoint"),
+		BucketName:         ctx.String("bucket-name"),
+		BucketRegion:       ctx.String("bucket-region"),
+		VirtualHostedStyle: ctx.Bool("virtual-hosted-style"),
+	}
+
+	err := validateBackupTarget(target)
+	if err != nil {
+		return err
+	}
+
+	if target.Type == settings.S3BackupType {
+		if ctx.String("credentials-secret") != "" {
+			secretNS, secretName, err := parseSecretRef(ctx.String("credentials-secret"))
+			if err != nil {
+				return err
+			}
+
+			accessKeyID, secretAccessKey, err := getBackupTargetCredentials(ctx, secretNS, secretName)
+			if err != nil {
+				return err
+			}
+			target.AccessKeyID = accessKeyID
+			target.SecretAccessKey = secretAccessKey
+		}
+
+		if ctx.String("cert-file") != "" {
+			cert, err := os.ReadFile(ctx.String("cert-file"))
+			if err != nil {
+				return fmt.Errorf("error during reading of certificate file: %w", err)
+			}
+			target.Cert = string(cert)
+		}
+	}
+
+	targetJSON, err := json.Marshal(target)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup target: %w", err)
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	setting, err := c.HarvesterhciV1beta1().Settings().Get(context.TODO(), settings.BackupTargetSettingName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("encountered issue when querying Harvester for setting %s: %w", settings.BackupTargetSettingName, err)
+	}
+
+	setting.Value = string(targetJSON)
+	_, err = c.HarvesterhciV1beta1().Settings().Update(context.TODO(), setting, k8smetav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update setting %s: %w", settings.BackupTargetSettingName, err)
+	}
+
+	logrus.Infof("Backup target set to %s", target.Endpoint)
+	return nil
+}
+
+// validateBackupTarget checks the type of a backup target and the fields this type requires
+func validateBackupTarget(target *settings.BackupTarget) error {
+	switch target.Type {
+	case settings.NFSBackupType:
+		if !strings.HasPrefix(target.Endpoint, "nfs://") {
+			return fmt.Errorf("the endpoint of an nfs backup target must start with nfs://")
+		}
+	case settings.S3BackupType:
+		if target.BucketName == "" || target.BucketRegion == "" {
+			return fmt.Errorf("--bucket-name and --bucket-region are required for an s3 backup target")
+		}
+	default:
+		return fmt.Errorf("invalid backup target type: %v, must be \"nfs\" or \"s3\"", target.Type)
+	}
+	return nil
+}
+
+// parseSecretRef splits a secret reference in the format <namespace>/<secret-name>
+func parseSecretRef(secretRef string) (secretNS string, secretName string, err error) {
+	parts := strings.Split(secretRef, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid secret %q, it must be given in the format <namespace>/<secret-name>", secretRef)
+	}
+	return parts[0], parts[1], nil
+}
+
+// getBackupTargetCredentials reads the S3 access key ID and secret access key from a secret
+func getBackupTargetCredentials(ctx *cli.Context, secretNS string, secretName string) (accessKeyID string, secretAccessKey string, err error) {
+	k, err := GetKubeClient(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := k.CoreV1().Secrets(secretNS).Get(context.TODO(), secretName, k8smetav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("error during getting credentials secret: %w", err)
+	}
+
+	return backupTargetCredentials(secret)
+}
+
+// backupTargetCredentials extracts the S3 access key ID and secret access key from the data of a secret
+func backupTargetCredentials(secret *corev1.Secret) (accessKeyID string, secretAccessKey string, err error) {
+	for _, key := range backupTargetAccessKeyNames {
+		if value, ok := secret.Data[key]; ok {
+			accessKeyID = string(value)
+			break
+		}
+	}
+
+	for _, key := range backupTargetSecretKeyNames {
+		if value, ok := secret.Data[key]; ok {
+			secretAccessKey = string(value)
+			break
+		}
+	}
+
+	if accessKeyID == "" || secretAccessKey == "" {
+		return "", "", fmt.Errorf("secret %s/%s must contain the keys %s and %s", secret.Namespace, secret.Name, backupTargetAccessKeyNames[0], backupTargetSecretKeyNames[0])
+	}
+
+	return accessKeyID, secretAccessKey, nil
+}
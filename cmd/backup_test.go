@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBackupTargetCredentials(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: "s3-creds", Namespace: "longhorn-system"},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("AKIAEXAMPLE"),
+			"AWS_SECRET_ACCESS_KEY": []byte("s3cr3t"),
+		},
+	}
+
+	accessKeyID, secretAccessKey, err := backupTargetCredentials(secret)
+	if err != nil || accessKeyID != "AKIAEXAMPLE" || secretAccessKey != "s3cr3t" {
+		t.Errorf("Expected the credentials of the secret, got %q %q (%v)", accessKeyID, secretAccessKey, err)
+	}
+
+	secret.Data = map[string][]byte{
+		"accessKeyId":     []byte("minio"),
+		"secretAccessKey": []byte("minio123"),
+	}
+	accessKeyID, secretAccessKey, err = backupTargetCredentials(secret)
+	if err != nil || accessKeyID != "minio" || secretAccessKey != "minio123" {
+		t.Errorf("Expected the credentials under the alternative keys, got %q %q (%v)", accessKeyID, secretAccessKey, err)
+	}
+
+	delete(secret.Data, "secretAccessKey")
+	_, _, err = backupTargetCredentials(secret)
+	if err == nil {
+		t.Errorf("Expected an error for a secret without secret access key")
+	}
+}
+
+func TestParseSecretRef(t *testing.T) {
+	secretNS, secretName, err := parseSecretRef("longhorn-system/s3-creds")
+	if err != nil || secretNS != "longhorn-system" || secretName != "s3-creds" {
+		t.Errorf("Expected longhorn-system/s3-creds, got %q %q (%v)", secretNS, secretName, err)
+	}
+
+	for _, secretRef := range []string{"s3-creds", "/s3-creds", "longhorn-system/", "a/b/c"} {
+		_, _, err := parseSecretRef(secretRef)
+		if err == nil {
+			t.Errorf("Expected an error for the secret %q", secretRef)
+		}
+	}
+}
+
+func TestBuildBackupTargetData(t *testing.T) {
+	target := &settings.BackupTarget{
+		Type:            settings.S3BackupType,
+		Endpoint:        "https://s3.example.com",
+		BucketName:      "backups",
+		BucketRegion:    "us-east-1",
+		AccessKeyID:     "AKIAEXAMPLE",
+		SecretAccessKey: "s3cr3t",
+	}
+	setting := &v1beta1.Setting{
+		Status: v1beta1.SettingStatus{
+			Conditions: []v1beta1.Condition{{Type: "configured", Status: corev1.ConditionTrue}},
+		},
+	}
+
+	toShow := buildBackupTargetData(target, setting)
+	expected := BackupTargetData{
+		Type:            "s3",
+		Endpoint:        "https://s3.example.com",
+		BucketName:      "backups",
+		BucketRegion:    "us-east-1",
+		AccessKeyID:     "AKIAEXAMPLE",
+		SecretAccessKey: hiddenSecretValue,
+		Conditions:      []string{"configured=True"},
+	}
+	if !reflect.DeepEqual(toShow, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, toShow)
+	}
+
+	target.SecretAccessKey = ""
+	if toShow := buildBackupTargetData(target, setting); toShow.SecretAccessKey != "" {
+		t.Errorf("Expected no secret access key to show, got %q", toShow.SecretAccessKey)
+	}
+}
+
+func TestValidateBackupTarget(t *testing.T) {
+	tests := []struct {
+		target settings.BackupTarget
+		valid  bool
+	}{
+		{settings.BackupTarget{Type: settings.NFSBackupType, Endpoint: "nfs://nfs.example.com:/backups"}, true},
+		{settings.BackupTarget{Type: settings.S3BackupType, Endpoint: "https://s3.example.com", BucketName: "backups", BucketRegion: "us-east-1", VirtualHostedStyle: true}, true},
+		{settings.BackupTarget{Type: settings.NFSBackupType, Endpoint: "nfs.example.com:/backups"}, false},
+		{settings.BackupTarget{Type: settings.S3BackupType, Endpoint: "https://s3.example.com", BucketName: "backups"}, false},
+		{settings.BackupTarget{Type: "ftp", Endpoint: "ftp://ftp.example.com"}, false},
+	}
+
+	for _, test := range tests {
+		err := validateBackupTarget(&test.target)
+		if test.valid && err != nil {
+			t.Errorf("Expected the backup target %+v to be valid, got %v", test.target, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected an error for the backup target %+v", test.target)
+		}
+	}
+}
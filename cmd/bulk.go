@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	defaultVMOperationParallelism = 10
+	bulkDescription               = ". The VMs are changed in parallel and a summary of the results is printed at the end. " +
+		"The VMs matching a selector, a wildcard or selected with --all-namespaces are listed and the operation must be confirmed, unless --yes is set"
+)
+
+// VMOperationResult is a Data Structure that holds the result of an operation on a VM
+type VMOperationResult struct {
+	Namespace string
+	VM        string
+	Result    string
+	Error     string
+}
+
+// bulkFlags returns the flags of the commands which change several VMs at once
+func bulkFlags() []cli.Flag {
+	return []cli.Flag{
+		&selectorFlag,
+		&allNamespacesFlag,
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Do not ask for confirmation before changing the VMs matching the selector, the wildcards or in all the namespaces",
+		},
+		&cli.IntFlag{
+			Name:    "parallel",
+			Aliases: []string{"p"},
+			Usage:   "Maximum number of VMs changed at the same time",
+			EnvVars: []string{"HARVESTER_VM_PARALLEL"},
+			Value:   defaultVMOperationParallelism,
+		},
+	}
+}
+
+// selectVMs returns the VMs named vmNames, which may contain wildcards, and the VMs matching the label selector, each VM only once
+// with --all-namespaces, the names are looked up in all the namespaces rather than in the one given by --namespace
+func selectVMs(c *harvclient.Clientset, ctx *cli.Context, vmNames []string) ([]VMv1.VirtualMachine, error) {
+	if len(vmNames) == 0 && !ctx.IsSet(selectorFlag.Name) {
+		return nil, fmt.Errorf("at least one VM name or a selector is required")
+	}
+
+	var vms []VMv1.VirtualMachine
+	selected := map[string]bool{}
+	addVM := func(vm VMv1.VirtualMachine) {
+		key := vm.Namespace + "/" + vm.Name
+		if !selected[key] {
+			selected[key] = true
+			vms = append(vms, vm)
+		}
+	}
+
+	if ctx.IsSet(selectorFlag.Name) {
+		vmList, err := c.KubevirtV1().VirtualMachines(listNamespace(ctx)).List(context.TODO(), k8smetav1.ListOptions{
+			LabelSelector: ctx.String(selectorFlag.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error during listing of VMs: %w", err)
+		}
+		for _, vm := range vmList.Items {
+			addVM(vm)
+		}
+	}
+
+	for _, vmName := range vmNames {
+		isPattern := isVMNamePattern(vmName)
+		if !isPattern && !ctx.Bool(allNamespacesFlag.Name) {
+			vm, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("no VM with the provided name %s found: %w", vmName, err)
+			}
+			addVM(*vm)
+			continue
+		}
+
+		matchingVMs, err := buildVMListMatchingWildcard(c, ctx, vmName)
+		if err != nil {
+			return nil, err
+		}
+		if len(matchingVMs) == 0 && !isPattern {
+			return nil, fmt.Errorf("no VM with the provided name %s found in any namespace", vmName)
+		}
+		for _, vm := range matchingVMs {
+			addVM(vm)
+		}
+	}
+
+	return vms, nil
+}
+
+// isVMNamePattern returns true if a VM name given as argument contains wildcards
+func isVMNamePattern(vmName string) bool {
+	return strings.ContainsAny(vmName, "*?")
+}
+
+// vmOperationNeedsConfirmation returns true if the VMs of an operation are not all named explicitly,
+// i.e. they are selected by a label selector, a wildcard or looked up in all the namespaces
+func vmOperationNeedsConfirmation(vmNames []string, selector bool, allNamespaces bool) bool {
+	if selector || allNamespaces {
+		return true
+	}
+	for _, vmName := range vmNames {
+		if isVMNamePattern(vmName) {
+			return true
+		}
+	}
+	return false
+}
+
+// runVMOperation applies an operation to the selected VMs concurrently, then prints the result for each VM
+// the VMs which are not all named explicitly are previewed and the operation must be confirmed, unless --yes is set
+func runVMOperation(ctx *cli.Context, c *harvclient.Clientset, operation string, fn func(vm *VMv1.VirtualMachine) error) error {
+	vms, err := selectVMs(c, ctx, ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+	if len(vms) == 0 {
+		return fmt.Errorf("no VM matching the selector found")
+	}
+
+	needsConfirmation := vmOperationNeedsConfirmation(ctx.Args().Slice(), ctx.IsSet(selectorFlag.Name), ctx.Bool(allNamespacesFlag.Name))
+	if needsConfirmation && !ctx.Bool("yes") {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("the %s of %d VMs must be confirmed, use --yes when not running in a terminal", operation, len(vms))
+		}
+		confirmed, err := confirmVMOperation(os.Stdin, os.Stdout, operation, vms)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return fmt.Errorf("%s cancelled", operation)
+		}
+	}
+
+	results := applyVMOperation(vms, ctx.Int("parallel"), fn)
+
+	writer := newTableWriter(ctx, [][]string{
+		{"NAMESPACE", "Namespace"},
+		{"VM", "VM"},
+		{"RESULT", "Result"},
+		{"ERROR", "Error"},
+	})
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+		writer.Write(result)
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%s failed on %d of %d VMs", operation, failed, len(results))
+	}
+	return nil
+}
+
+// confirmVMOperation prints the VMs affected by an operation and asks whether to proceed, only an answer starting with y confirms it
+func confirmVMOperation(in io.Reader, out io.Writer, operation string, vms []VMv1.VirtualMachine) (bool, error) {
+	writer := newTableWriterWithOptions(out, outputOptions{format: outputTable}, [][]string{
+		{"NAMESPACE", "VirtualMachine.Namespace"},
+		{"NAME", "Name"},
+		{"STATE", "State"},
+	})
+	for _, vm := range vms {
+		writer.Write(&VirtualMachineData{
+			State:          string(vm.Status.PrintableStatus),
+			VirtualMachine: vm,
+			Name:           vm.Name,
+		})
+	}
+	err := writer.Close()
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(out, "\nDo you want to %s these %d VMs? [y/N]: ", operation, len(vms))
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y"), nil
+}
+
+// applyVMOperation calls fn on the VMs with at most parallel calls at the same time, the results are in the order of the VMs
+func applyVMOperation(vms []VMv1.VirtualMachine, parallel int, fn func(vm *VMv1.VirtualMachine) error) []VMOperationResult {
+	results := make([]VMOperationResult, len(vms))
+	runInParallel(len(vms), parallel, func(i int) {
+		results[i] = VMOperationResult{
+			Namespace: vms[i].Namespace,
+			VM:        vms[i].Name,
+			Result:    "OK",
+		}
+		err := fn(&vms[i])
+		if err != nil {
+			results[i].Result = "FAILED"
+			results[i].Error = err.Error()
+		}
+	})
+	return results
+}
+
+// runInParallel calls fn with each index from 0 to n-1, with at most parallel calls at the same time, and returns when all the calls are done
+func runInParallel(n int, parallel int, fn func(i int)) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func newBulkTestVMs(names ...string) []VMv1.VirtualMachine {
+	var vms []VMv1.VirtualMachine
+	for _, name := range names {
+		vms = append(vms, VMv1.VirtualMachine{
+			ObjectMeta: k8smetav1.ObjectMeta{Name: name, Namespace: "staging"},
+			Status:     VMv1.VirtualMachineStatus{PrintableStatus: VMv1.VirtualMachineStatusRunning},
+		})
+	}
+	return vms
+}
+
+func TestSelectVMs(t *testing.T) {
+	c := newTestHarvesterClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vmName, isGet := strings.CutPrefix(r.URL.Path, "/apis/kubevirt.io/v1/namespaces/staging/virtualmachines/")
+		switch {
+		case r.URL.Path == "/apis/kubevirt.io/v1/namespaces/staging/virtualmachines":
+			vms := newBulkTestVMs("web-1", "web-2", "db-1")
+			if r.URL.Query().Get("labelSelector") == "app=web" {
+				vms = vms[:2]
+			}
+			writeTestJSON(t, w, &VMv1.VirtualMachineList{
+				TypeMeta: k8smetav1.TypeMeta{Kind: "VirtualMachineList", APIVersion: "kubevirt.io/v1"},
+				Items:    vms,
+			})
+		case isGet:
+			vm := newBulkTestVMs(vmName)[0]
+			vm.TypeMeta = k8smetav1.TypeMeta{Kind: "VirtualMachine", APIVersion: "kubevirt.io/v1"}
+			writeTestJSON(t, w, &vm)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	flags := flag.NewFlagSet("exec", flag.ContinueOnError)
+	flags.String("namespace", "staging", "")
+	flags.String(selectorFlag.Name, "", "")
+	flags.Bool(allNamespacesFlag.Name, false, "")
+	if err := flags.Parse([]string{"--selector", "app=web"}); err != nil {
+		t.Fatalf("Error parsing flags: %v", err)
+	}
+	ctx := cli.NewContext(cli.NewApp(), flags, nil)
+
+	vms, err := selectVMs(c, ctx, []string{"web-*", "db-1", "web-1"})
+	if err != nil {
+		t.Fatalf("Error selecting VMs: %v", err)
+	}
+
+	var names []string
+	for _, vm := range vms {
+		names = append(names, vm.Namespace+"/"+vm.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"staging/web-1", "staging/web-2", "staging/db-1"}) {
+		t.Errorf("Expected each VM to be selected once, got %v", names)
+	}
+}
+
+func TestApplyVMOperation(t *testing.T) {
+	vms := newBulkTestVMs("vm1", "vm2", "vm3", "vm4", "vm5")
+
+	var running, maxRunning int32
+	results := applyVMOperation(vms, 2, func(vm *VMv1.VirtualMachine) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if vm.Name == "vm3" {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+
+	if maxRunning > 2 {
+		t.Errorf("Expected at most 2 operations at the same time, got %d", maxRunning)
+	}
+	if len(results) != len(vms) {
+		t.Fatalf("Expected %d results, got %d", len(vms), len(results))
+	}
+	for i, result := range results {
+		expected := VMOperationResult{Namespace: "staging", VM: vms[i].Name, Result: "OK"}
+		if vms[i].Name == "vm3" {
+			expected.Result = "FAILED"
+			expected.Error = "boom"
+		}
+		if result != expected {
+			t.Errorf("Expected %+v, got %+v", expected, result)
+		}
+	}
+}
+
+func TestRunInParallel(t *testing.T) {
+	for _, parallel := range []int{0, 1, 3, 20} {
+		var calls [10]int32
+		runInParallel(len(calls), parallel, func(i int) {
+			atomic.AddInt32(&calls[i], 1)
+		})
+		for i, count := range calls {
+			if count != 1 {
+				t.Errorf("Expected index %d to be called once with parallel %d, got %d", i, parallel, count)
+			}
+		}
+	}
+}
+
+func TestConfirmVMOperation(t *testing.T) {
+	tests := []struct {
+		answer   string
+		expected bool
+	}{
+		{"y\n", true},
+		{"Yes\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		confirmed, err := confirmVMOperation(strings.NewReader(test.answer), out, "stop", newBulkTestVMs("vm1", "vm2"))
+		if err != nil || confirmed != test.expected {
+			t.Errorf("Expected %t for the answer %q, got %t (%v)", test.expected, test.answer, confirmed, err)
+		}
+
+		if !strings.Contains(out.String(), "staging     vm1       Running") || !strings.Contains(out.String(), "Do you want to stop these 2 VMs? [y/N]") {
+			t.Errorf("Expected a preview of the VMs and a question, got %q", out.String())
+		}
+	}
+}
+
+func TestVMOperationNeedsConfirmation(t *testing.T) {
+	tests := []struct {
+		vmNames       []string
+		selector      bool
+		allNamespaces bool
+		expected      bool
+	}{
+		{[]string{"vm1", "vm2"}, false, false, false},
+		{nil, true, false, true},
+		{[]string{"web-*"}, false, false, true},
+		{[]string{"vm1", "db-?"}, false, false, true},
+		{[]string{"vm1"}, false, true, true},
+		{[]string{"*"}, false, true, true},
+	}
+
+	for _, test := range tests {
+		needsConfirmation := vmOperationNeedsConfirmation(test.vmNames, test.selector, test.allNamespaces)
+		if needsConfirmation != test.expected {
+			t.Errorf("Expected %t for %v with selector %t and all namespaces %t, got %t",
+				test.expected, test.vmNames, test.selector, test.allNamespaces, needsConfirmation)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	catalogCacheFolder = "catalogs"
+	checksumTypeSHA512 = "sha512"
+)
+
+// fetchCatalogFromContext fetches and merges the catalogs given by the --metadata-url flags, using the local cache unless --no-cache is set
+func fetchCatalogFromContext(ctx *cli.Context) (*Catalog, error) {
+	cacheDir := ""
+	if !ctx.Bool("no-cache") {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			logrus.Warn("Not able to determine home folder of current user, catalogs will not be cached!")
+		} else {
+			cacheDir = filepath.Join(userHome, ".harvester", "cache", catalogCacheFolder)
+		}
+	}
+
+	return fetchCatalog(ctx.StringSlice("metadata-url"), cacheDir)
+}
+
+// fetchCatalog fetches every catalog source and merges them into one catalog, in the order of the sources.
+// An empty cacheDir disables the cache.
+func fetchCatalog(sources []string, cacheDir string) (*Catalog, error) {
+	catalog := &Catalog{
+		HarvesterImageCatalog: map[string][]CatalogEntry{},
+	}
+
+	for _, source := range sources {
+		logrus.Debug("current metadata url: " + source)
+
+		body, err := fetchCatalogSource(source, cacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch catalog %s: %w", source, err)
+		}
+
+		var sourceCatalog Catalog
+		err = json.Unmarshal(body, &sourceCatalog)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse catalog %s: %w", source, err)
+		}
+
+		mergeCatalog(catalog, &sourceCatalog)
+	}
+
+	return catalog, nil
+}
+
+// mergeCatalog adds the entries of src to dst, entries which URL is already present for the same OS are skipped
+func mergeCatalog(dst *Catalog, src *Catalog) {
+	for osName, entries := range src.HarvesterImageCatalog {
+		knownURLs := map[string]bool{}
+		for _, entry := range dst.HarvesterImageCatalog[osName] {
+			knownURLs[entry.Url] = true
+		}
+
+		for _, entry := range entries {
+			if knownURLs[entry.Url] {
+				continue
+			}
+			knownURLs[entry.Url] = true
+			dst.HarvesterImageCatalog[osName] = append(dst.HarvesterImageCatalog[osName], entry)
+		}
+	}
+}
+
+// fetchCatalogSource returns the content of a catalog, which source is either an HTTP(S) link, a file:// link or a local path
+func fetchCatalogSource(source string, cacheDir string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return fetchRemoteCatalog(source, cacheDir)
+	}
+
+	if strings.HasPrefix(source, "file://") {
+		sourceURL, err := url.Parse(source)
+		if err != nil {
+			return nil, err
+		}
+		source = sourceURL.Path
+	}
+
+	return os.ReadFile(source)
+}
+
+// fetchRemoteCatalog downloads a catalog over HTTP(S).
+// The last downloaded version is kept in the cache folder together with its ETag, which is used to revalidate it.
+// If the server can't be reached, the cached version is used.
+func fetchRemoteCatalog(source string, cacheDir string) ([]byte, error) {
+	var cachePath, etagPath string
+	var cached []byte
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(source))
+		cachePath = filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json")
+		etagPath = cachePath + ".etag"
+		cached, _ = os.ReadFile(cachePath)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil {
+		if etag, err := os.ReadFile(etagPath); err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if cached != nil {
+			logrus.Warnf("Unable to reach %s (%s), using cached catalog", source, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		logrus.Debugf("Catalog %s not modified, using cached catalog", source)
+		return cached, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if cached != nil {
+			logrus.Warnf("Fetching %s returned %s, using cached catalog", source, resp.Status)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		err = writeCatalogCache(cachePath, etagPath, body, resp.Header.Get("ETag"))
+		if err != nil {
+			logrus.Warnf("Unable to cache catalog %s: %s", source, err)
+		}
+	}
+
+	return body, nil
+}
+
+// writeCatalogCache stores a catalog and its ETag in the cache folder
+func writeCatalogCache(cachePath string, etagPath string, body []byte, etag string) error {
+	err := os.MkdirAll(filepath.Dir(cachePath), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(cachePath, body, 0600)
+	if err != nil {
+		return err
+	}
+
+	if etag == "" {
+		err = os.Remove(etagPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return os.WriteFile(etagPath, []byte(etag), 0600)
+}
+
+// catalogEntryChecksum returns the checksum of a catalog entry that Harvester can verify after download.
+// Harvester only verifies SHA512 checksums, other checksum types are ignored.
+func catalogEntryChecksum(entry CatalogEntry) string {
+	if entry.Checksum == "" {
+		return ""
+	}
+
+	if entry.ChecksumType != "" && !strings.EqualFold(entry.ChecksumType, checksumTypeSHA512) {
+		logrus.Warnf("Checksum type %s of image %s is not supported by Harvester, only %s is, the download will not be verified", entry.ChecksumType, entry.Url, checksumTypeSHA512)
+		return ""
+	}
+
+	return strings.ToLower(entry.Checksum)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	teamCatalog   = `{"HarvesterImageCatalog":{"ubuntu":[{"shortName":"Team Jammy","version":"22.04","build":"current","url":"https://images.example.com/jammy.img","checksum":"ABCDEF","checksumType":"sha512"}]}}`
+	vendorCatalog = `{"HarvesterImageCatalog":{"ubuntu":[{"shortName":"Jammy","version":"22.04","build":"current","url":"https://images.example.com/jammy.img"},{"shortName":"Focal","version":"20.04","build":"current","url":"https://images.example.com/focal.img"}]}}`
+)
+
+func TestFetchCatalogMergesSourcesAndRevalidatesCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(vendorCatalog))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	teamCatalogPath := filepath.Join(tmpDir, "team.json")
+	if err := os.WriteFile(teamCatalogPath, []byte(teamCatalog), 0600); err != nil {
+		t.Fatalf("Error writing team catalog: %v", err)
+	}
+	cacheDir := filepath.Join(tmpDir, "cache")
+
+	for _, teamSource := range []string{teamCatalogPath, "file://" + teamCatalogPath} {
+		catalog, err := fetchCatalog([]string{teamSource, server.URL}, cacheDir)
+		if err != nil {
+			t.Fatalf("Error fetching catalog: %v", err)
+		}
+
+		entries := catalog.HarvesterImageCatalog["ubuntu"]
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 merged entries, got %v", entries)
+		}
+		if entries[0].ShortName != "Team Jammy" || entries[1].ShortName != "Focal" {
+			t.Errorf("Expected the team entry to take precedence over the vendor one, got %v", entries)
+		}
+		if catalogEntryChecksum(entries[0]) != "abcdef" {
+			t.Errorf("Expected checksum abcdef, got %s", catalogEntryChecksum(entries[0]))
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to the remote catalog, got %d", requests)
+	}
+
+	server.Close()
+	catalog, err := fetchCatalog([]string{server.URL}, cacheDir)
+	if err != nil {
+		t.Fatalf("Expected the cached catalog to be used when offline, got %v", err)
+	}
+	if len(catalog.HarvesterImageCatalog["ubuntu"]) != 2 {
+		t.Errorf("Expected the 2 cached entries, got %v", catalog.HarvesterImageCatalog["ubuntu"])
+	}
+}
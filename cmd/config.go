@@ -12,12 +12,23 @@ import (
 	"github.com/sirupsen/logrus"
 	cliv1 "github.com/urfave/cli"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	kubeConfigFilename = "config"
 )
 
+// CLIConfig holds the settings of the harvester CLI which are read from its configuration file
+type CLIConfig struct {
+	VNC VNCConfig `yaml:"vnc"`
+}
+
+// VNCConfig holds the settings of the `vm vnc` command
+type VNCConfig struct {
+	Viewer string `yaml:"viewer"`
+}
+
 // Conf is an Object that contains the configuration path and the configuration's file content as a string
 type Conf struct {
 	Path    string
@@ -128,3 +139,31 @@ func createKubeconfigFile(config Conf) error {
 	logrus.Infof("Successfully written %d bytes to %s", l, config.Path)
 	return err
 }
+
+// loadCLIConfig reads the configuration file of the harvester CLI given by --cli-config
+func loadCLIConfig(ctx *cli.Context) (*CLIConfig, error) {
+	return readCLIConfig(os.ExpandEnv(ctx.String("cli-config")))
+}
+
+// readCLIConfig reads a configuration file of the harvester CLI, a missing file is an empty configuration
+func readCLIConfig(configPath string) (*CLIConfig, error) {
+	cliConfig := &CLIConfig{}
+	if configPath == "" {
+		return cliConfig, nil
+	}
+
+	content, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return cliConfig, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error during reading of the CLI configuration file %s: %w", configPath, err)
+	}
+
+	err = yaml.Unmarshal(content, cliConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error during decoding of the CLI configuration file %s: %w", configPath, err)
+	}
+
+	return cliConfig, nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCLIConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	cliConfig, err := readCLIConfig(configPath)
+	if err != nil || cliConfig.VNC.Viewer != "" {
+		t.Errorf("Expected an empty configuration for a missing file, got %+v (%v)", cliConfig, err)
+	}
+
+	err = os.WriteFile(configPath, []byte("vnc:\n  viewer: remote-viewer vnc://{}\n"), 0600)
+	if err != nil {
+		t.Fatalf("Error writing configuration file: %v", err)
+	}
+	cliConfig, err = readCLIConfig(configPath)
+	if err != nil || cliConfig.VNC.Viewer != "remote-viewer vnc://{}" {
+		t.Errorf("Expected the viewer of the configuration file, got %+v (%v)", cliConfig, err)
+	}
+
+	err = os.WriteFile(configPath, []byte("vnc: [\n"), 0600)
+	if err != nil {
+		t.Fatalf("Error writing configuration file: %v", err)
+	}
+	_, err = readCLIConfig(configPath)
+	if err == nil {
+		t.Errorf("Expected an error for an invalid configuration file")
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gorilla/websocket"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+const defaultConsoleEscape = "^]"
+
+// vmConsoleCommand defines the CLI sub-command `vm console` that attaches the terminal to the serial console of a VM
+func vmConsoleCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "console",
+		Usage:       "Attach to the serial console of a VM",
+		Description: "\nAttaches the terminal to the serial console of the VM given as argument, which works even when the network or SSH server of the guest is broken",
+		ArgsUsage:   "VM_NAME",
+		Action:      vmConsole,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:    "escape",
+				Usage:   "Character to detach from the console, either a single character or ^ followed by a character for a control character",
+				EnvVars: []string{"HARVESTER_CONSOLE_ESCAPE"},
+				Value:   defaultConsoleEscape,
+			},
+		},
+	}
+}
+
+// vmConsole implements the `vm console` command
+func vmConsole(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("one and only one argument is accepted for this command, and that is the vm name")
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	escape, err := parseEscapeChar(ctx.String("escape"))
+	if err != nil {
+		return err
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = getRunningVMI(c, namespace, vmName)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialVMISubresource(ctx, namespace, vmName, "console")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("error during switching the terminal to raw mode: %w", err)
+		}
+		defer func() {
+			_ = term.Restore(stdinFd, oldState)
+		}()
+	}
+
+	fmt.Fprintf(os.Stderr, "Connected to the serial console of VM %s, press %s to detach\r\n", vmName, ctx.String("escape"))
+
+	done := make(chan error, 2)
+	go func() {
+		done <- copyStdinToConsole(conn, os.Stdin, escape)
+	}()
+	go func() {
+		done <- copyWebsocketToWriter(os.Stdout, conn)
+	}()
+
+	err = <-done
+	fmt.Fprint(os.Stderr, "\r\nDetached from the serial console\r\n")
+	return err
+}
+
+// parseEscapeChar converts an escape character given as a single character or in caret notation, such as ^], to its byte value
+func parseEscapeChar(escape string) (byte, error) {
+	switch {
+	case len(escape) == 1:
+		return escape[0], nil
+	case len(escape) == 2 && escape[0] == '^' && escape[1] == '?':
+		return 0x7f, nil
+	case len(escape) == 2 && escape[0] == '^' && escape[1] >= '@' && escape[1] <= '_':
+		return escape[1] & 0x1f, nil
+	case len(escape) == 2 && escape[0] == '^' && escape[1] >= 'a' && escape[1] <= 'z':
+		return escape[1] & 0x1f, nil
+	default:
+		return 0, fmt.Errorf("invalid escape character %q, it must be a single character or ^ followed by a character", escape)
+	}
+}
+
+// copyStdinToConsole sends the input to the console until the escape character is typed, which returns nil
+func copyStdinToConsole(conn *websocket.Conn, in io.Reader, escape byte) error {
+	buf := make([]byte, 1024)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			escapeIndex := bytes.IndexByte(data, escape)
+			if escapeIndex >= 0 {
+				data = data[:escapeIndex]
+			}
+
+			if len(data) > 0 {
+				writeErr := conn.WriteMessage(websocket.BinaryMessage, data)
+				if writeErr != nil {
+					return fmt.Errorf("error during writing to the console: %w", writeErr)
+				}
+			}
+
+			if escapeIndex >= 0 {
+				return nil
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error during reading the input: %w", err)
+		}
+	}
+}
+
+// copyWebsocketToWriter writes the messages received on a websocket until the connection is closed
+func copyWebsocketToWriter(out io.Writer, conn *websocket.Conn) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return fmt.Errorf("error during reading from the websocket: %w", err)
+		}
+
+		_, err = out.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+}
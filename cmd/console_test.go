@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestParseEscapeChar(t *testing.T) {
+	cases := map[string]byte{
+		"^]": 0x1d,
+		"^c": 0x03,
+		"^?": 0x7f,
+		"~":  '~',
+	}
+
+	for escape, expected := range cases {
+		value, err := parseEscapeChar(escape)
+		if err != nil || value != expected {
+			t.Errorf("Expected %#x for %s, got %#x (%v)", expected, escape, value, err)
+		}
+	}
+
+	_, err := parseEscapeChar("ctrl-]")
+	if err == nil {
+		t.Errorf("Expected an error for an invalid escape character")
+	}
+}
@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	kubeclient "k8s.io/client-go/kubernetes"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+// vmDescription is a Data Structure that holds a VM and the resources related to it
+type vmDescription struct {
+	VM     *VMv1.VirtualMachine           `json:"vm"`
+	VMI    *VMv1.VirtualMachineInstance   `json:"vmi,omitempty"`
+	Pod    *corev1.Pod                    `json:"pod,omitempty"`
+	PVCs   []corev1.PersistentVolumeClaim `json:"pvcs,omitempty"`
+	Events []corev1.Event                 `json:"events,omitempty"`
+}
+
+// vmDescribeCommand defines the CLI sub-command `vm describe` that prints a VM and its related resources
+func vmDescribeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "describe",
+		Usage: "Show the details of a VM and its related resources",
+		Description: "\nPrints the spec and status of the VM given as argument, with its VMI, virt-launcher pod, volumes, networks " +
+			"and the recent events of all of them, like kubectl describe",
+		ArgsUsage: "VM_NAME",
+		Action:    vmDescribe,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&outputFlag,
+		},
+	}
+}
+
+// vmDescribe implements the `vm describe` command
+func vmDescribe(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("one and only one argument is accepted for this command, and that is the vm name")
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	k, err := GetKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	vm, err := c.KubevirtV1().VirtualMachines(namespace).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error during fetching of VM %s: %w", vmName, err)
+	}
+
+	description := vmDescription{VM: vm}
+
+	vmi, err := c.KubevirtV1().VirtualMachineInstances(namespace).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+	if err == nil {
+		description.VMI = vmi
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("error during fetching of VMI %s: %w", vmName, err)
+	}
+
+	if description.VMI != nil {
+		description.Pod, err = findVMILauncherPod(k, description.VMI)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, pvcName := range vmPVCNames(vm) {
+		pvc, err := k.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), pvcName, k8smetav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			description.PVCs = append(description.PVCs, corev1.PersistentVolumeClaim{
+				ObjectMeta: k8smetav1.ObjectMeta{Name: pvcName},
+				Status:     corev1.PersistentVolumeClaimStatus{Phase: "NotFound"},
+			})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error during fetching of volume %s: %w", pvcName, err)
+		}
+		description.PVCs = append(description.PVCs, *pvc)
+	}
+
+	objectNames := []string{vmName}
+	if description.Pod != nil {
+		objectNames = append(objectNames, description.Pod.Name)
+	}
+	for _, pvc := range description.PVCs {
+		objectNames = append(objectNames, pvc.Name)
+	}
+	for _, objectName := range objectNames {
+		events, err := k.CoreV1().Events(namespace).List(context.TODO(), k8smetav1.ListOptions{
+			FieldSelector: "involvedObject.name=" + objectName,
+		})
+		if err != nil {
+			return fmt.Errorf("error during listing of the events of %s: %w", objectName, err)
+		}
+		description.Events = append(description.Events, events.Items...)
+	}
+
+	if printed, err := printObject(ctx, description); printed || err != nil {
+		return err
+	}
+
+	return printVMDescription(os.Stdout, description, time.Now())
+}
+
+// findVMILauncherPod returns the most recent virt-launcher pod created for a VMI, older ones belong to previous migrations, or nil if there is none
+func findVMILauncherPod(k *kubeclient.Clientset, vmi *VMv1.VirtualMachineInstance) (*corev1.Pod, error) {
+	pods, err := k.CoreV1().Pods(vmi.Namespace).List(context.TODO(), k8smetav1.ListOptions{
+		LabelSelector: VMv1.CreatedByLabel + "=" + string(vmi.UID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error during listing of the pods of VM %s: %w", vmi.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	return &pods.Items[0], nil
+}
+
+// vmPVCNames returns the names of the PVCs used by a VM, those created from the volume claim templates annotation first
+func vmPVCNames(vm *VMv1.VirtualMachine) []string {
+	var names []string
+	seen := map[string]bool{}
+	addName := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	var claimTemplates []corev1.PersistentVolumeClaim
+	if annotation := vm.Annotations[vmAnnotationPVC]; annotation != "" {
+		err := json.Unmarshal([]byte(annotation), &claimTemplates)
+		if err == nil {
+			for _, claimTemplate := range claimTemplates {
+				addName(claimTemplate.Name)
+			}
+		}
+	}
+
+	if vm.Spec.Template != nil {
+		for _, volume := range vm.Spec.Template.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				addName(volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+
+	return names
+}
+
+// printVMDescription prints the description of a VM in sections, in the same layout as kubectl describe
+func printVMDescription(out io.Writer, d vmDescription, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	vm := d.VM
+
+	fmt.Fprintf(w, "Name:\t%s\n", vm.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", vm.Namespace)
+	fmt.Fprintf(w, "Labels:\t%s\n", formatLabels(vm.Labels))
+	fmt.Fprintf(w, "Created:\t%s\n", vm.CreationTimestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Status:\t%s\n", vm.Status.PrintableStatus)
+	if vm.Spec.RunStrategy != nil {
+		fmt.Fprintf(w, "Run Strategy:\t%s\n", *vm.Spec.RunStrategy)
+	} else if vm.Spec.Running != nil {
+		fmt.Fprintf(w, "Running:\t%t\n", *vm.Spec.Running)
+	}
+
+	if vm.Spec.Template != nil {
+		spec := vm.Spec.Template.Spec
+		fmt.Fprintf(w, "Spec:\n")
+		if spec.Domain.CPU != nil {
+			fmt.Fprintf(w, "  CPU:\t%d cores, %d sockets, %d threads\n", spec.Domain.CPU.Cores, spec.Domain.CPU.Sockets, spec.Domain.CPU.Threads)
+		}
+		fmt.Fprintf(w, "  Memory:\t%s\n", vmMemory(vm))
+		if spec.Domain.Machine != nil {
+			fmt.Fprintf(w, "  Machine Type:\t%s\n", spec.Domain.Machine.Type)
+		}
+		if spec.NodeSelector != nil {
+			fmt.Fprintf(w, "  Node Selector:\t%s\n", formatLabels(spec.NodeSelector))
+		}
+
+		fmt.Fprintf(w, "  Disks:\n")
+		for _, disk := range spec.Domain.Devices.Disks {
+			fmt.Fprintf(w, "    %s:\t%s\n", disk.Name, describeDisk(disk, spec.Volumes))
+		}
+
+		fmt.Fprintf(w, "  Networks:\n")
+		for _, network := range spec.Networks {
+			fmt.Fprintf(w, "    %s:\t%s\n", network.Name, describeNetwork(network, spec.Domain.Devices.Interfaces))
+		}
+	}
+
+	fmt.Fprintf(w, "Conditions:\n")
+	if len(vm.Status.Conditions) == 0 {
+		fmt.Fprintf(w, "  <none>\n")
+	} else {
+		fmt.Fprintf(w, "  Type\tStatus\tReason\tMessage\n")
+		for _, condition := range vm.Status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+
+	fmt.Fprintf(w, "Instance:")
+	if d.VMI == nil {
+		fmt.Fprintf(w, "\t<none>\n")
+	} else {
+		vmi := d.VMI
+		fmt.Fprintf(w, "\n  Phase:\t%s\n", vmi.Status.Phase)
+		fmt.Fprintf(w, "  Node:\t%s\n", vmi.Status.NodeName)
+		if vmi.Status.GuestOSInfo.PrettyName != "" {
+			fmt.Fprintf(w, "  Guest OS:\t%s\n", vmi.Status.GuestOSInfo.PrettyName)
+		}
+		if vmi.Status.MigrationState != nil {
+			fmt.Fprintf(w, "  Last Migration:\t%s -> %s, completed: %t, failed: %t\n", vmi.Status.MigrationState.SourceNode,
+				vmi.Status.MigrationState.TargetNode, vmi.Status.MigrationState.Completed, vmi.Status.MigrationState.Failed)
+		}
+		fmt.Fprintf(w, "  Interfaces:\n")
+		for _, iface := range vmi.Status.Interfaces {
+			fmt.Fprintf(w, "    %s:\t%s %s\n", iface.Name, iface.MAC, strings.Join(interfaceIPs(iface), ","))
+		}
+		fmt.Fprintf(w, "  Conditions:\n")
+		for _, condition := range vmi.Status.Conditions {
+			fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+		}
+	}
+
+	fmt.Fprintf(w, "Pod:")
+	if d.Pod == nil {
+		fmt.Fprintf(w, "\t<none>\n")
+	} else {
+		pod := d.Pod
+		fmt.Fprintf(w, "\n  Name:\t%s\n", pod.Name)
+		fmt.Fprintf(w, "  Phase:\t%s\n", pod.Status.Phase)
+		fmt.Fprintf(w, "  Node:\t%s\n", pod.Spec.NodeName)
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			fmt.Fprintf(w, "  Container %s:\t%s, %d restarts\n", containerStatus.Name, describeContainerState(containerStatus.State), containerStatus.RestartCount)
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				fmt.Fprintf(w, "  %s:\t%s %s %s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Volumes:\n")
+	if len(d.PVCs) == 0 {
+		fmt.Fprintf(w, "  <none>\n")
+	} else {
+		fmt.Fprintf(w, "  Name\tStatus\tCapacity\tStorage Class\n")
+		for _, pvc := range d.PVCs {
+			capacity := ""
+			if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+				capacity = storage.String()
+			}
+			storageClass := ""
+			if pvc.Spec.StorageClassName != nil {
+				storageClass = *pvc.Spec.StorageClassName
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", pvc.Name, pvc.Status.Phase, capacity, storageClass)
+		}
+	}
+
+	fmt.Fprintf(w, "Events:")
+	if len(d.Events) == 0 {
+		fmt.Fprintf(w, "\t<none>\n")
+	} else {
+		sort.Slice(d.Events, func(i, j int) bool {
+			return eventTime(d.Events[i]).Before(eventTime(d.Events[j]))
+		})
+		fmt.Fprintf(w, "\n  Type\tReason\tAge\tObject\tMessage\n")
+		for _, event := range d.Events {
+			age := duration.HumanDuration(now.Sub(eventTime(event)))
+			object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", event.Type, event.Reason, age, object, strings.TrimSpace(event.Message))
+		}
+	}
+
+	return w.Flush()
+}
+
+// vmMemory returns the memory limit of a VM, or its request if there is no limit
+func vmMemory(vm *VMv1.VirtualMachine) string {
+	resources := vm.Spec.Template.Spec.Domain.Resources
+	if resources.Limits.Memory().CmpInt64(int64(0)) == 0 {
+		return resources.Requests.Memory().String()
+	}
+	return resources.Limits.Memory().String()
+}
+
+// describeDisk returns the bus and the source of a disk
+func describeDisk(disk VMv1.Disk, volumes []VMv1.Volume) string {
+	var bus, source string
+	switch {
+	case disk.Disk != nil:
+		bus = "disk/" + string(disk.Disk.Bus)
+	case disk.CDRom != nil:
+		bus = "cdrom/" + string(disk.CDRom.Bus)
+	case disk.LUN != nil:
+		bus = "lun/" + string(disk.LUN.Bus)
+	}
+
+	for _, volume := range volumes {
+		if volume.Name != disk.Name {
+			continue
+		}
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			source = "pvc " + volume.PersistentVolumeClaim.ClaimName
+		case volume.ContainerDisk != nil:
+			source = "container disk " + volume.ContainerDisk.Image
+		case volume.CloudInitNoCloud != nil:
+			source = "cloud-init nocloud"
+		case volume.CloudInitConfigDrive != nil:
+			source = "cloud-init config drive"
+		case volume.DataVolume != nil:
+			source = "data volume " + volume.DataVolume.Name
+		default:
+			source = "other"
+		}
+	}
+
+	return strings.TrimSpace(bus + " " + source)
+}
+
+// describeNetwork returns the type and the interface model of a network
+func describeNetwork(network VMv1.Network, interfaces []VMv1.Interface) string {
+	description := "pod"
+	if network.Multus != nil {
+		description = "bridge " + network.Multus.NetworkName
+	}
+
+	for _, iface := range interfaces {
+		if iface.Name == network.Name {
+			description += ", model " + iface.Model
+			if iface.MacAddress != "" {
+				description += ", MAC " + iface.MacAddress
+			}
+		}
+	}
+
+	return description
+}
+
+// describeContainerState returns the state of a container with its reason
+func describeContainerState(state corev1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running"
+	case state.Waiting != nil:
+		return "Waiting (" + state.Waiting.Reason + ")"
+	case state.Terminated != nil:
+		return fmt.Sprintf("Terminated (%s, exit code %d)", state.Terminated.Reason, state.Terminated.ExitCode)
+	default:
+		return "Unknown"
+	}
+}
+
+// eventTime returns the last time an event occurred
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// formatLabels returns labels sorted by key in the format key=value, separated by commas
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "<none>"
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestVMPVCNames(t *testing.T) {
+	vm := newTestVM("vm1")
+	vm.Annotations = map[string]string{
+		vmAnnotationPVC: `[{"metadata":{"name":"vm1-disk-0-abcde"}}]`,
+	}
+	vm.Spec.Template.Spec.Volumes = []VMv1.Volume{
+		{Name: "disk-0", VolumeSource: VMv1.VolumeSource{PersistentVolumeClaim: &VMv1.PersistentVolumeClaimVolumeSource{
+			PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "vm1-disk-0-abcde"},
+		}}},
+		{Name: "disk-1", VolumeSource: VMv1.VolumeSource{PersistentVolumeClaim: &VMv1.PersistentVolumeClaimVolumeSource{
+			PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+		}}},
+		{Name: "cloudinit", VolumeSource: VMv1.VolumeSource{CloudInitNoCloud: &VMv1.CloudInitNoCloudSource{}}},
+	}
+
+	names := vmPVCNames(vm)
+	if !reflect.DeepEqual(names, []string{"vm1-disk-0-abcde", "data"}) {
+		t.Errorf("Expected the PVCs of the annotation and of the volumes, got %v", names)
+	}
+}
+
+func TestPrintVMDescription(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	vm := newTestVM("vm1")
+	vm.Status.PrintableStatus = VMv1.VirtualMachineStatusUnschedulable
+
+	var out bytes.Buffer
+	err := printVMDescription(&out, vmDescription{
+		VM: vm,
+		Events: []corev1.Event{{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "virt-launcher-vm1-abcde"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "FailedScheduling",
+			Message:        "0/3 nodes are available: 3 Insufficient memory.",
+			LastTimestamp:  k8smetav1.NewTime(now.Add(-5 * time.Minute)),
+		}},
+	}, now)
+	if err != nil {
+		t.Fatalf("Error printing description: %v", err)
+	}
+
+	for _, expected := range []string{"ErrorUnschedulable", "Instance:  <none>", "FailedScheduling", "5m", "pod/virt-launcher-vm1-abcde"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in description:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestFindVMILauncherPod(t *testing.T) {
+	now := time.Now()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/pods" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+
+		podList := &corev1.PodList{TypeMeta: k8smetav1.TypeMeta{Kind: "PodList", APIVersion: "v1"}}
+		if r.URL.Query().Get("labelSelector") == "kubevirt.io/created-by=uid-web" {
+			podList.Items = []corev1.Pod{
+				{ObjectMeta: k8smetav1.ObjectMeta{Name: "virt-launcher-web-abcde", CreationTimestamp: k8smetav1.NewTime(now.Add(-time.Hour))}},
+				{ObjectMeta: k8smetav1.ObjectMeta{Name: "virt-launcher-web-fghij", CreationTimestamp: k8smetav1.NewTime(now)}},
+			}
+		}
+		writeTestJSON(t, w, podList)
+	}))
+	defer server.Close()
+
+	k, err := kubeclient.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Error creating Kubernetes client: %v", err)
+	}
+
+	vmi := &VMv1.VirtualMachineInstance{ObjectMeta: k8smetav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-web"}}
+	pod, err := findVMILauncherPod(k, vmi)
+	if err != nil || pod == nil || pod.Name != "virt-launcher-web-fghij" {
+		t.Errorf("Expected the most recent pod created for the VMI, got %v (%v)", pod, err)
+	}
+
+	vmi.UID = "uid-other"
+	pod, err = findVMILauncherPod(k, vmi)
+	if err != nil || pod != nil {
+		t.Errorf("Expected no pod for a VMI without launcher pod, got %v (%v)", pod, err)
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const eventLineFormat = "%-20s  %-8s  %-24s  %-50s  %s\n"
+
+// EventsCommand defines the CLI command that streams the Kubernetes events of Harvester and KubeVirt objects
+func EventsCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "events",
+		Usage:       "Stream the events of Harvester and KubeVirt objects",
+		Description: "\nPrints the existing events of Harvester and KubeVirt objects, and of the virt-launcher pods of the VMs, then the new ones as they happen, until interrupted",
+		Action:      streamEvents,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:  "vm",
+				Usage: "Only print the events of this VM, its VMI and its virt-launcher pods",
+			},
+		},
+	}
+}
+
+// streamEvents implements the `events` command, the watch is resumed from the last event when the API server closes it,
+// or restarted from the current events if that version is too old, without printing again the events already printed
+func streamEvents(ctx *cli.Context) error {
+	k, err := GetKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	vmName := ctx.String("vm")
+	fmt.Printf(eventLineFormat, "TIME", "TYPE", "REASON", "OBJECT", "MESSAGE")
+
+	printed := printedEvents{}
+	resourceVersion := ""
+	for {
+		watcher, err := k.CoreV1().Events(ctx.String("namespace")).Watch(context.TODO(), k8smetav1.ListOptions{
+			ResourceVersion: resourceVersion,
+		})
+		if isWatchExpired(err) && resourceVersion != "" {
+			resourceVersion = ""
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error during watching of events: %w", err)
+		}
+
+		for watchEvent := range watcher.ResultChan() {
+			if watchEvent.Type == watch.Error {
+				err := errors.FromObject(watchEvent.Object)
+				if isWatchExpired(err) {
+					logrus.Debugf("Restarting the watch of events: %v", err)
+					resourceVersion = ""
+					break
+				}
+				watcher.Stop()
+				return fmt.Errorf("error during watching of events: %w", err)
+			}
+
+			event, ok := watchEvent.Object.(*corev1.Event)
+			if !ok {
+				continue
+			}
+			resourceVersion = event.ResourceVersion
+
+			if watchEvent.Type == watch.Deleted {
+				printed.forget(event)
+				continue
+			}
+			if !isVMEvent(event, vmName) || !printed.add(event) {
+				continue
+			}
+			printEvent(os.Stdout, event)
+		}
+		watcher.Stop()
+	}
+}
+
+// isWatchExpired returns true if a watch failed because the resource version it started from is too old
+func isWatchExpired(err error) bool {
+	return errors.IsResourceExpired(err) || errors.IsGone(err)
+}
+
+// printedEvents holds the resource version of the events printed, so that the events listed again when the watch is restarted are not printed twice
+type printedEvents map[types.UID]string
+
+// add records the version of an event, it returns false if this version has already been printed
+func (p printedEvents) add(event *corev1.Event) bool {
+	if p[event.UID] == event.ResourceVersion {
+		return false
+	}
+	p[event.UID] = event.ResourceVersion
+	return true
+}
+
+// forget removes a deleted event, which cannot be listed again
+func (p printedEvents) forget(event *corev1.Event) {
+	delete(p, event.UID)
+}
+
+// isVMEvent returns true if an event concerns a Harvester or KubeVirt object, or a virt-launcher pod, restricted to the objects of a VM if vmName is not empty
+func isVMEvent(event *corev1.Event, vmName string) bool {
+	object := event.InvolvedObject
+	group := schema.FromAPIVersionAndKind(object.APIVersion, object.Kind).Group
+	harvesterObject := group == "kubevirt.io" || strings.HasSuffix(group, ".kubevirt.io") ||
+		group == "harvesterhci.io" || strings.HasSuffix(group, ".harvesterhci.io")
+	launcherPod := object.Kind == "Pod" && strings.HasPrefix(object.Name, "virt-launcher-")
+
+	if vmName == "" {
+		return harvesterObject || launcherPod
+	}
+
+	return (harvesterObject && object.Name == vmName) ||
+		(launcherPod && isVMLauncherPodName(object.Name, vmName))
+}
+
+// isVMLauncherPodName returns true if podName is the name of a virt-launcher pod of the VM vmName, i.e. the name of the VM followed by the 5 characters generated for the pod.
+// The pods of the VMs which names start with the same prefix, e.g. vmName-1, do not match.
+func isVMLauncherPodName(podName string, vmName string) bool {
+	suffix, found := strings.CutPrefix(podName, "virt-launcher-"+vmName+"-")
+	return found && len(suffix) == 5 && !strings.Contains(suffix, "-")
+}
+
+// printEvent prints an event on a single line
+func printEvent(out io.Writer, event *corev1.Event) {
+	object := strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name
+	fmt.Fprintf(out, eventLineFormat, eventTime(*event).Local().Format(time.DateTime), event.Type, event.Reason, object, strings.TrimSpace(event.Message))
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsVMEvent(t *testing.T) {
+	newEvent := func(apiVersion string, kind string, name string) *corev1.Event {
+		return &corev1.Event{InvolvedObject: corev1.ObjectReference{APIVersion: apiVersion, Kind: kind, Name: name}}
+	}
+
+	cases := []struct {
+		event    *corev1.Event
+		vmName   string
+		expected bool
+	}{
+		{newEvent("kubevirt.io/v1", "VirtualMachine", "vm1"), "", true},
+		{newEvent("harvesterhci.io/v1beta1", "VirtualMachineImage", "image-abcde"), "", true},
+		{newEvent("v1", "Pod", "virt-launcher-vm1-abcde"), "", true},
+		{newEvent("v1", "Pod", "nginx-abcde"), "", false},
+		{newEvent("apps/v1", "Deployment", "vm1"), "", false},
+		{newEvent("kubevirt.io/v1", "VirtualMachineInstance", "vm1"), "vm1", true},
+		{newEvent("v1", "Pod", "virt-launcher-vm1-abcde"), "vm1", true},
+		{newEvent("v1", "Pod", "virt-launcher-vm10-abcde"), "vm1", false},
+		{newEvent("v1", "Pod", "virt-launcher-vm1-2-abcde"), "vm1", false},
+		{newEvent("v1", "Pod", "virt-launcher-vm1-2-abcde"), "vm1-2", true},
+		{newEvent("kubevirt.io/v1", "VirtualMachine", "vm2"), "vm1", false},
+	}
+
+	for _, c := range cases {
+		if isVMEvent(c.event, c.vmName) != c.expected {
+			t.Errorf("Expected %t for %s %s with VM filter %q", c.expected, c.event.InvolvedObject.Kind, c.event.InvolvedObject.Name, c.vmName)
+		}
+	}
+}
+
+func TestIsWatchExpired(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.NewResourceExpired("too old resource version: 1234 (5678)"), true},
+		{errors.NewGone("too old resource version"), true},
+		{errors.FromObject(&k8smetav1.Status{Status: k8smetav1.StatusFailure, Code: 410, Reason: k8smetav1.StatusReasonExpired}), true},
+		{errors.NewForbidden(schema.GroupResource{Resource: "events"}, "", fmt.Errorf("denied")), false},
+		{nil, false},
+	}
+
+	for _, c := range cases {
+		if expired := isWatchExpired(c.err); expired != c.expected {
+			t.Errorf("Expected %t for %v, got %t", c.expected, c.err, expired)
+		}
+	}
+}
+
+func TestPrintedEvents(t *testing.T) {
+	printed := printedEvents{}
+	event := &corev1.Event{ObjectMeta: k8smetav1.ObjectMeta{UID: "uid-1", ResourceVersion: "10"}}
+
+	if !printed.add(event) {
+		t.Errorf("Expected a new event to be printed")
+	}
+	if printed.add(event) {
+		t.Errorf("Expected an event listed again by a restarted watch not to be printed twice")
+	}
+
+	event.ResourceVersion = "11"
+	if !printed.add(event) {
+		t.Errorf("Expected an updated event to be printed")
+	}
+
+	printed.forget(event)
+	if len(printed) != 0 {
+		t.Errorf("Expected a deleted event to be forgotten, got %v", printed)
+	}
+}
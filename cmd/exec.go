@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const defaultExecParallelism = 10
+
+// VMExecResult is a Data Structure that holds the result of a command executed on a VM
+type VMExecResult struct {
+	Namespace string
+	VM        string
+	ExitCode  string
+	Error     string
+}
+
+// ExecCommand defines the CLI command that runs a command over SSH on several VMs in parallel
+func ExecCommand() *cli.Command {
+	flags := []cli.Flag{
+		&nsFlag,
+		&selectorFlag,
+		&allNamespacesFlag,
+		&cli.IntFlag{
+			Name:    "parallel",
+			Aliases: []string{"p"},
+			Usage:   "Maximum number of VMs on which the command runs at the same time",
+			EnvVars: []string{"HARVESTER_EXEC_PARALLEL"},
+			Value:   defaultExecParallelism,
+		},
+		&cli.IntFlag{
+			Name:    "ssh-port",
+			Usage:   "TCP port to be used to connect to the VMs using SSH",
+			EnvVars: []string{"HARVESTER_VM_SSH_PORT"},
+			Value:   22,
+		},
+		&cli.BoolFlag{
+			Name:    "pod-network",
+			Usage:   "Connect to the VMs through the pod network",
+			EnvVars: []string{"HARVESTER_VM_POD_NETWORK"},
+		},
+	}
+
+	return &cli.Command{
+		Name:  "exec",
+		Usage: "Run a command over SSH on several VMs in parallel",
+		Description: "\nRuns a command on the VMs matching the selector or the VM names, which may contain wildcards, given before --. " +
+			"With --all-namespaces, the VMs are looked up in all the namespaces. " +
+			"The output is prefixed with the VM name and a summary of the exit codes is printed at the end",
+		ArgsUsage: "[VM_NAME...] -- COMMAND [ARGS...]",
+		Action:    vmExec,
+		Flags:     append(append(flags, sshFlags()...), OutputFlags()...),
+	}
+}
+
+// vmExec implements the `exec` command
+func vmExec(ctx *cli.Context) error {
+	vmPatterns, command := splitExecArgs(ctx.Args().Slice(), ctx.IsSet(selectorFlag.Name))
+	if command == "" {
+		return fmt.Errorf("a command to run is required after --")
+	}
+	if len(vmPatterns) == 0 && !ctx.IsSet(selectorFlag.Name) {
+		return fmt.Errorf("either VM names or a --selector are required")
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	k, err := GetKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	restConf, err := GetRESTClientAndConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	vms, err := selectVMs(c, ctx, vmPatterns)
+	if err != nil {
+		return err
+	}
+	if len(vms) == 0 {
+		return fmt.Errorf("no VM matching the selector found")
+	}
+
+	opts := sshOptionsFromContext(ctx)
+	opts.Command = command
+	opts.NoPrompt = true
+	clientConfig, closeAgent, err := sshClientConfig(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAgent()
+
+	var outputLock sync.Mutex
+	results := make([]VMExecResult, len(vms))
+	runInParallel(len(vms), ctx.Int("parallel"), func(i int) {
+		vm := &vms[i]
+		stdout := newPrefixWriter(os.Stdout, vm.Name, &outputLock)
+		stderr := newPrefixWriter(os.Stderr, vm.Name, &outputLock)
+
+		err := func() error {
+			client, err := dialVMSSH(c, k, restConf, vm.Namespace, vm.Name, ctx.Int("ssh-port"), ctx.Bool("pod-network"), clientConfig)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			return runSSHCommand(client, command, stdout, stderr)
+		}()
+
+		stdout.Flush()
+		stderr.Flush()
+		results[i] = buildVMExecResult(vm, err)
+	})
+
+	writer := newTableWriter(ctx, [][]string{
+		{"NAMESPACE", "Namespace"},
+		{"VM", "VM"},
+		{"EXIT CODE", "ExitCode"},
+		{"ERROR", "Error"},
+	})
+
+	failed := 0
+	for _, result := range results {
+		if result.ExitCode != "0" {
+			failed++
+		}
+		writer.Write(result)
+	}
+	writer.Close()
+	if writer.Err() != nil {
+		return writer.Err()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d VMs", failed, len(results))
+	}
+	return nil
+}
+
+// splitExecArgs separates the VM names from the command, either at the -- separator, or after the first argument unless a selector is used
+func splitExecArgs(args []string, selector bool) (vmPatterns []string, command string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], strings.Join(args[i+1:], " ")
+		}
+	}
+
+	if selector || len(args) == 0 {
+		return nil, strings.Join(args, " ")
+	}
+	return args[:1], strings.Join(args[1:], " ")
+}
+
+// runSSHCommand runs a command in a new session of the SSH client
+func runSSHCommand(client *ssh.Client, command string, stdout io.Writer, stderr io.Writer) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("error during creation of SSH session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	return session.Run(command)
+}
+
+// buildVMExecResult creates an object to display from the error returned by a command, an exit code of -1 means the command could not be run
+func buildVMExecResult(vm *VMv1.VirtualMachine, err error) VMExecResult {
+	result := VMExecResult{Namespace: vm.Namespace, VM: vm.Name}
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = "0"
+	case errors.As(err, &exitErr):
+		result.ExitCode = fmt.Sprint(exitErr.ExitStatus())
+	default:
+		result.ExitCode = "-1"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// prefixWriter writes complete lines prefixed with a name, so that the outputs of several VMs can be interleaved
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	lock   *sync.Mutex
+	buf    []byte
+}
+
+func newPrefixWriter(out io.Writer, name string, lock *sync.Mutex) *prefixWriter {
+	return &prefixWriter{
+		out:    out,
+		prefix: "[" + name + "] ",
+		lock:   lock,
+	}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		end := bytes.IndexByte(w.buf, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+
+		err := w.writeLine(w.buf[:end+1])
+		w.buf = w.buf[end+1:]
+		if err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes the last line if it does not end with a newline
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		_ = w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestSplitExecArgs(t *testing.T) {
+	vmPatterns, command := splitExecArgs([]string{"web-*", "db-1", "--", "uptime", "-p"}, false)
+	if !reflect.DeepEqual(vmPatterns, []string{"web-*", "db-1"}) || command != "uptime -p" {
+		t.Errorf("Unexpected split %v %q", vmPatterns, command)
+	}
+
+	vmPatterns, command = splitExecArgs([]string{"df", "-h"}, true)
+	if len(vmPatterns) != 0 || command != "df -h" {
+		t.Errorf("Unexpected split with selector %v %q", vmPatterns, command)
+	}
+
+	vmPatterns, command = splitExecArgs([]string{"web-1", "hostname"}, false)
+	if !reflect.DeepEqual(vmPatterns, []string{"web-1"}) || command != "hostname" {
+		t.Errorf("Unexpected split without separator %v %q", vmPatterns, command)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var lock sync.Mutex
+	w := newPrefixWriter(&out, "web-1", &lock)
+
+	_, _ = w.Write([]byte("line one\nline "))
+	_, _ = w.Write([]byte("two\npartial"))
+	w.Flush()
+
+	expected := "[web-1] line one\n[web-1] line two\n[web-1] partial\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestBuildVMExecResult(t *testing.T) {
+	vm := &VMv1.VirtualMachine{ObjectMeta: k8smetav1.ObjectMeta{Name: "web-1", Namespace: "staging"}}
+	if result := buildVMExecResult(vm, nil); result.ExitCode != "0" || result.Namespace != "staging" || result.VM != "web-1" {
+		t.Errorf("Expected exit code 0 for staging/web-1, got %v", result)
+	}
+	if result := buildVMExecResult(vm, errors.New("connection refused")); result.ExitCode != "-1" || result.Error == "" {
+		t.Errorf("Expected exit code -1 with an error, got %v", result)
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+// VMGuestInfo is a Data Structure that holds the information reported by the guest agent of a VM
+type VMGuestInfo struct {
+	Name              string                                        `json:"name"`
+	Hostname          string                                        `json:"hostname"`
+	GuestAgentVersion string                                        `json:"guestAgentVersion"`
+	Timezone          string                                        `json:"timezone"`
+	OS                VMv1.VirtualMachineInstanceGuestOSInfo        `json:"os"`
+	Users             []VMv1.VirtualMachineInstanceGuestOSUser      `json:"users"`
+	Filesystems       []VMv1.VirtualMachineInstanceFileSystem       `json:"filesystems"`
+	Interfaces        []VMv1.VirtualMachineInstanceNetworkInterface `json:"interfaces"`
+}
+
+// VMGuestUserData is a Data Structure that holds a user logged in a VM for display
+type VMGuestUserData struct {
+	User      string
+	Domain    string
+	LoginTime string
+}
+
+// VMFilesystemData is a Data Structure that holds a filesystem of a VM for display
+type VMFilesystemData struct {
+	Disk       string
+	MountPoint string
+	Type       string
+	Used       string
+	Total      string
+	Usage      string
+}
+
+// VMInterfaceData is a Data Structure that holds a network interface of a VM for display
+type VMInterfaceData struct {
+	Name          string
+	InterfaceName string
+	MAC           string
+	IPs           string
+}
+
+// vmGuestInfoCommand defines the CLI sub-command `vm guest-info` that prints the information reported by the guest agent
+func vmGuestInfoCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "guest-info",
+		Usage:       "Print the OS, users, filesystems and network interfaces reported by the guest agent of a VM",
+		Description: "\nQueries the guestosinfo, userlist and filesystemlist subresources of the VM given as argument, which requires the QEMU guest agent to run in the VM",
+		ArgsUsage:   "VM_NAME",
+		Action:      vmGuestInfo,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&outputFlag,
+			&noHeadersFlag,
+		},
+	}
+}
+
+// vmGuestInfo implements the `vm guest-info` command
+func vmGuestInfo(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("one and only one argument is accepted for this command, and that is the vm name")
+	}
+
+	// the output format is checked before querying the guest agent
+	_, err := getOutputOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmi, err := getRunningVMI(c, namespace, vmName)
+	if err != nil {
+		return err
+	}
+
+	if !guestAgentConnected(vmi) {
+		return fmt.Errorf("the guest agent of VM %s is not connected, it must be installed and running in the VM", vmName)
+	}
+
+	var agentInfo VMv1.VirtualMachineInstanceGuestAgentInfo
+	err = getVMISubresource(c, namespace, vmName, "guestosinfo", &agentInfo)
+	if err != nil {
+		return err
+	}
+
+	var userList VMv1.VirtualMachineInstanceGuestOSUserList
+	err = getVMISubresource(c, namespace, vmName, "userlist", &userList)
+	if err != nil {
+		return err
+	}
+
+	var filesystemList VMv1.VirtualMachineInstanceFileSystemList
+	err = getVMISubresource(c, namespace, vmName, "filesystemlist", &filesystemList)
+	if err != nil {
+		return err
+	}
+
+	guestInfo := VMGuestInfo{
+		Name:              vmName,
+		Hostname:          agentInfo.Hostname,
+		GuestAgentVersion: agentInfo.GAVersion,
+		Timezone:          agentInfo.Timezone,
+		OS:                agentInfo.OS,
+		Users:             userList.Items,
+		Filesystems:       filesystemList.Items,
+		Interfaces:        vmi.Status.Interfaces,
+	}
+
+	if printed, err := printObject(ctx, guestInfo); printed || err != nil {
+		return err
+	}
+
+	return printGuestInfo(ctx, guestInfo)
+}
+
+// guestAgentConnected returns true if the VMI reports a connected guest agent
+func guestAgentConnected(vmi *VMv1.VirtualMachineInstance) bool {
+	for _, condition := range vmi.Status.Conditions {
+		if condition.Type == VMv1.VirtualMachineInstanceAgentConnected {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// printGuestInfo prints the guest information as a summary followed by a table of users, of filesystems and of interfaces
+func printGuestInfo(ctx *cli.Context, guestInfo VMGuestInfo) error {
+	osName := guestInfo.OS.PrettyName
+	if osName == "" {
+		osName = strings.TrimSpace(guestInfo.OS.Name + " " + guestInfo.OS.Version)
+	}
+
+	fmt.Printf("Hostname:       %s\n", guestInfo.Hostname)
+	fmt.Printf("OS:             %s\n", osName)
+	fmt.Printf("Kernel:         %s %s\n", guestInfo.OS.KernelRelease, guestInfo.OS.Machine)
+	fmt.Printf("Timezone:       %s\n", guestInfo.Timezone)
+	fmt.Printf("Guest Agent:    %s\n", guestInfo.GuestAgentVersion)
+	fmt.Println()
+
+	userWriter := newTableWriter(ctx, [][]string{
+		{"USER", "User"},
+		{"DOMAIN", "Domain"},
+		{"LOGIN TIME", "LoginTime"},
+	})
+	for _, user := range guestInfo.Users {
+		userWriter.Write(&VMGuestUserData{
+			User:      user.UserName,
+			Domain:    user.Domain,
+			LoginTime: time.Unix(int64(user.LoginTime), 0).Format(time.RFC3339),
+		})
+	}
+	userWriter.Close()
+	if userWriter.Err() != nil {
+		return userWriter.Err()
+	}
+	fmt.Println()
+
+	filesystemWriter := newTableWriter(ctx, [][]string{
+		{"DISK", "Disk"},
+		{"MOUNT POINT", "MountPoint"},
+		{"TYPE", "Type"},
+		{"USED", "Used"},
+		{"TOTAL", "Total"},
+		{"USE%", "Usage"},
+	})
+	for _, filesystem := range guestInfo.Filesystems {
+		usage := ""
+		if filesystem.TotalBytes > 0 {
+			usage = fmt.Sprintf("%d%%", int64(filesystem.UsedBytes)*100/int64(filesystem.TotalBytes))
+		}
+		filesystemWriter.Write(&VMFilesystemData{
+			Disk:       filesystem.DiskName,
+			MountPoint: filesystem.MountPoint,
+			Type:       filesystem.FileSystemType,
+			Used:       humanBytes(int64(filesystem.UsedBytes)),
+			Total:      humanBytes(int64(filesystem.TotalBytes)),
+			Usage:      usage,
+		})
+	}
+	filesystemWriter.Close()
+	if filesystemWriter.Err() != nil {
+		return filesystemWriter.Err()
+	}
+	fmt.Println()
+
+	interfaceWriter := newTableWriter(ctx, [][]string{
+		{"NAME", "Name"},
+		{"INTERFACE", "InterfaceName"},
+		{"MAC", "MAC"},
+		{"IP ADDRESSES", "IPs"},
+	})
+	for _, iface := range guestInfo.Interfaces {
+		interfaceWriter.Write(&VMInterfaceData{
+			Name:          iface.Name,
+			InterfaceName: iface.InterfaceName,
+			MAC:           iface.MAC,
+			IPs:           strings.Join(interfaceIPs(iface), ","),
+		})
+	}
+	interfaceWriter.Close()
+	return interfaceWriter.Err()
+}
+
+// interfaceIPs returns all the IP addresses of an interface, falling back to its primary IP address when the list is empty
+func interfaceIPs(iface VMv1.VirtualMachineInstanceNetworkInterface) []string {
+	ips := iface.IPs
+	if len(ips) == 0 && iface.IP != "" {
+		ips = []string{iface.IP}
+	}
+	return ips
+}
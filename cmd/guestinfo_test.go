@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestInterfaceIPs(t *testing.T) {
+	iface := VMv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5", IPs: []string{"10.0.0.5", "fd00::5"}}
+	if ips := interfaceIPs(iface); !reflect.DeepEqual(ips, []string{"10.0.0.5", "fd00::5"}) {
+		t.Errorf("Expected both IP addresses, got %v", ips)
+	}
+
+	iface = VMv1.VirtualMachineInstanceNetworkInterface{IP: "10.0.0.5"}
+	if ips := interfaceIPs(iface); !reflect.DeepEqual(ips, []string{"10.0.0.5"}) {
+		t.Errorf("Expected the primary IP address, got %v", ips)
+	}
+}
+
+func TestGuestAgentConnected(t *testing.T) {
+	vmi := &VMv1.VirtualMachineInstance{}
+	if guestAgentConnected(vmi) {
+		t.Errorf("Expected the guest agent not to be connected without condition")
+	}
+
+	vmi.Status.Conditions = []VMv1.VirtualMachineInstanceCondition{
+		{Type: VMv1.VirtualMachineInstanceAgentConnected, Status: corev1.ConditionTrue},
+	}
+	if !guestAgentConnected(vmi) {
+		t.Errorf("Expected the guest agent to be connected")
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"k8s.io/client-go/rest"
+)
+
+// newTestHarvesterClient creates a Harvester client sending its requests to a test server served by handler
+func newTestHarvesterClient(t *testing.T, handler http.Handler) *harvclient.Clientset {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := harvclient.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Error creating Harvester client: %v", err)
+	}
+	return c
+}
+
+// writeTestJSON writes an API object as the JSON response of a test server
+func writeTestJSON(t *testing.T, w http.ResponseWriter, object interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(object)
+	if err != nil {
+		t.Errorf("Error encoding response: %v", err)
+	}
+}
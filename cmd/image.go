@@ -2,13 +2,8 @@ package cmd
 
 import (
 	"bufio"
-	"bytes"
+	"compress/gzip"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
-	"encoding/pem"
-	"flag"
 	"fmt"
 	"io"
 	"io/fs"
@@ -17,32 +12,72 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
-	rcmd "github.com/rancher/cli/cmd"
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/minio/pkg/wildcard"
 	"github.com/rancher/cli/config"
 	"github.com/sirupsen/logrus"
-	cliv1 "github.com/urfave/cli"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 type ImageData struct {
-	Name       string
-	Id         string
-	SourceType string
-	Url        string
+	Name                string
+	Id                  string
+	SourceType          string
+	Url                 string
+	Size                string
+	VirtualMachineImage v1beta1.VirtualMachineImage
+}
+
+// APIObject returns the VM image the display structure was built from
+func (d ImageData) APIObject() interface{} {
+	return d.VirtualMachineImage
+}
+
+// ImageDetails is a Data Structure that holds the spec and the status of a VM image to be displayed by `image show`
+type ImageDetails struct {
+	Id           string            `yaml:"id"`
+	Namespace    string            `yaml:"namespace"`
+	DisplayName  string            `yaml:"displayName"`
+	Description  string            `yaml:"description,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty"`
+	SourceType   string            `yaml:"sourceType"`
+	Url          string            `yaml:"url,omitempty"`
+	Checksum     string            `yaml:"checksum,omitempty"`
+	Progress     int               `yaml:"progress"`
+	Size         string            `yaml:"size"`
+	StorageClass string            `yaml:"storageClass,omitempty"`
+	Conditions   []ImageCondition  `yaml:"conditions,omitempty"`
+}
+
+// ImageCondition is a condition of a VM image as displayed by `image show`
+type ImageCondition struct {
+	Type               string `yaml:"type"`
+	Status             string `yaml:"status"`
+	Reason             string `yaml:"reason,omitempty"`
+	Message            string `yaml:"message,omitempty"`
+	LastTransitionTime string `yaml:"lastTransitionTime,omitempty"`
 }
 
 type CatalogEntry struct {
-	Id        int64  `json:"id,omitempty"`
-	ShortName string `json:"shortName"`
-	Version   string `json:"version"`
-	Url       string `json:"url"`
-	Build     string `json:"build"`
+	Id           int64  `json:"id,omitempty"`
+	ShortName    string `json:"shortName"`
+	Version      string `json:"version"`
+	Url          string `json:"url"`
+	Build        string `json:"build"`
+	Checksum     string `json:"checksum,omitempty"`
+	ChecksumType string `json:"checksumType,omitempty"`
 }
 
 type Catalog struct {
@@ -55,8 +90,13 @@ type Os struct {
 	NumberOfImages string
 }
 
+var (
+	uploadRetryDelay = 2 * time.Second
+)
+
 const (
-	defaultCatalogSource = "https://raw.githubusercontent.com/belgaied2/harvester-cli/feature-image-upload/image-metadata.json"
+	defaultCatalogSource    = "https://raw.githubusercontent.com/belgaied2/harvester-cli/feature-image-upload/image-metadata.json"
+	defaultImageWaitTimeout = 30 * time.Minute
 )
 
 // TemplateCommand defines the CLI command that lists VM templates in Harvester
@@ -66,9 +106,11 @@ func ImageCommand() *cli.Command {
 		Aliases: []string{"img"},
 		Usage:   "Manipulate VM images",
 		Action:  imageList,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&nsFlag,
-		},
+			&outputFlag,
+			&noHeadersFlag,
+		}, listFlags()...),
 		Subcommands: cli.Commands{
 			&cli.Command{
 				Name:        "list",
@@ -77,9 +119,12 @@ func ImageCommand() *cli.Command {
 				Description: "\nLists all the VM images available in Harvester",
 				ArgsUsage:   "",
 				Action:      imageList,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+					&watchFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				}, listFlags()...),
 			},
 			&cli.Command{
 				Name:        "create",
@@ -102,23 +147,135 @@ func ImageCommand() *cli.Command {
 						EnvVars:  []string{"HARVESTER_VM_IMAGE_DESCRIPTION"},
 						Required: false,
 					},
+					&cli.StringFlag{
+						Name:    "chunk-size",
+						Usage:   "Size of the chunks read from a local file and streamed to Harvester during upload, e.g. 4Mi or 64Mi",
+						EnvVars: []string{"HARVESTER_VM_IMAGE_CHUNK_SIZE"},
+						Value:   defaultTransferChunkSize,
+					},
+					&cli.IntFlag{
+						Name:    "retries",
+						Usage:   "Number of times an interrupted upload of a local file is retried",
+						EnvVars: []string{"HARVESTER_VM_IMAGE_RETRIES"},
+						Value:   3,
+					},
+				},
+			},
+			&cli.Command{
+				Name:        "download",
+				Aliases:     []string{"export"},
+				Usage:       "Downloads a VM image to a local file",
+				Description: "\nDownloads the content of a VM image from Harvester to a local file, a partial download can be resumed using the --resume flag",
+				ArgsUsage:   "IMAGE_ID",
+				Action:      imageDownload,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Path of the local file to which the image will be written, defaults to the display name of the image",
+						EnvVars: []string{"HARVESTER_VM_IMAGE_OUTPUT"},
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Resume a partial download of the output file instead of overwriting it",
+					},
+					&cli.BoolFlag{
+						Name:  "decompress",
+						Usage: "Decompress the gzip archive sent by Harvester into the output file",
+					},
+				},
+			},
+			&cli.Command{
+				Name:        "show",
+				Aliases:     []string{"get"},
+				Usage:       "Shows the details of a VM image",
+				Description: "\nShows the spec and status of the VM image given as an argument, including import progress, size, storage class and conditions",
+				ArgsUsage:   "IMAGE_ID",
+				Action:      imageShow,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&outputFlag,
+				},
+			},
+			&cli.Command{
+				Name:        "update",
+				Usage:       "Updates a VM image",
+				Description: "\nUpdates the display name, the description or the labels of the VM image given as an argument",
+				ArgsUsage:   "IMAGE_ID",
+				Action:      imageUpdate,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:  "display-name",
+						Usage: "New display name of the VM image",
+					},
+					&cli.StringFlag{
+						Name:  "description",
+						Usage: "New description of the VM image",
+					},
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "Label to set on the VM image in the format <key>=<value>, or <key>- to remove it, can be repeated",
+					},
+				},
+			},
+			&cli.Command{
+				Name: "delete",
+				Aliases: []string{
+					"del",
+					"rm",
+				},
+				Usage:       "Deletes VM images",
+				Description: "\nDeletes the VM images given as arguments, wildcards are matched against image IDs and display names",
+				ArgsUsage:   "[IMAGE_ID...]",
+				Action:      imageDelete,
+				Flags: []cli.Flag{
+					&nsFlag,
+				},
+			},
+			&cli.Command{
+				Name:        "wait",
+				Usage:       "Waits for a VM image to be imported",
+				Description: "\nBlocks until the VM image given as an argument is imported, fails to import, or the timeout expires",
+				ArgsUsage:   "IMAGE_ID",
+				Action:      imageWait,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.DurationFlag{
+						Name:    "timeout",
+						Usage:   "Maximum duration to wait for the image import",
+						EnvVars: []string{"HARVESTER_VM_IMAGE_WAIT_TIMEOUT"},
+						Value:   defaultImageWaitTimeout,
+					},
 				},
 			},
 			&cli.Command{
 				Name:        "catalog",
 				Aliases:     []string{"cat"},
 				Usage:       "lists an image catalog",
-				Description: "\nShows a list of freely available linux images to download from URLs",
+				Description: "\nShows a list of freely available linux images to download from URLs.\nWhen --os, --version or --build are given, the single matching image is created without prompting",
 				ArgsUsage:   "",
 				Action:      imageCatalog,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
 					&cli.StringFlag{
-						Name:     "metadata-url",
-						Usage:    "Location from which to get the metadata JSON file",
-						EnvVars:  []string{"HARVESTER_CATALOG_METADATA"},
-						Required: false,
-						Value:    defaultCatalogSource,
+						Name:  "display-name",
+						Usage: "Display name of the image to create, defaults to the file name in the image URL",
+					},
+				}, catalogFlags()...),
+				Subcommands: cli.Commands{
+					&cli.Command{
+						Name:        "list",
+						Aliases:     []string{"ls"},
+						Usage:       "Lists the images of the catalog",
+						Description: "\nPrints the images of the catalog matching the selectors, without creating anything",
+						ArgsUsage:   "",
+						Action:      imageCatalogList,
+						Flags: append([]cli.Flag{
+							&outputFlag,
+							&noHeadersFlag,
+						}, catalogFlags()...),
 					},
 				},
 			},
@@ -126,16 +283,39 @@ func ImageCommand() *cli.Command {
 	}
 }
 
-var (
-	ctxv1 = cliv1.NewContext(
-		&cliv1.App{
-			Name: "harvester",
+// catalogFlags returns the flags used to fetch the image catalog and select entries from it
+func catalogFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     "metadata-url",
+			Usage:    "Location from which to get the metadata JSON file, either an HTTP(S) link, a file:// link or a local path. Can be repeated to merge several catalogs",
+			EnvVars:  []string{"HARVESTER_CATALOG_METADATA"},
+			Required: false,
+			Value:    cli.NewStringSlice(defaultCatalogSource),
 		},
-		flag.NewFlagSet("", flag.ContinueOnError),
-		nil,
-	)
-)
+		&cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Do not use the local cache of catalogs fetched over HTTP",
+		},
+		&cli.StringFlag{
+			Name:    "os",
+			Usage:   "Select catalog images by OS, matching OS names starting with the given value",
+			EnvVars: []string{"HARVESTER_CATALOG_OS"},
+		},
+		&cli.StringFlag{
+			Name:    "version",
+			Usage:   "Select catalog images by version",
+			EnvVars: []string{"HARVESTER_CATALOG_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:    "build",
+			Usage:   "Select catalog images by build, e.g. current",
+			EnvVars: []string{"HARVESTER_CATALOG_BUILD"},
+		},
+	}
+}
 
+// imageList lists the VM images, and keeps listing them when they change if --watch is set
 func imageList(ctx *cli.Context) (err error) {
 	c, err := GetHarvesterClient(ctx)
 
@@ -143,34 +323,48 @@ func imageList(ctx *cli.Context) (err error) {
 		return
 	}
 
-	imgList, err := c.HarvesterhciV1beta1().VirtualMachineImages(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	namespace := listNamespace(ctx)
+	if !ctx.Bool("watch") {
+		return printImageList(ctx, c, namespace)
+	}
+
+	return renderOnChanges(func() error {
+		return printImageList(ctx, c, namespace)
+	}, func(watchCtx context.Context) (watch.Interface, error) {
+		return c.HarvesterhciV1beta1().VirtualMachineImages(namespace).Watch(watchCtx, listOptions(ctx))
+	})
+}
+
+// printImageList prints the VM images of a namespace, or of all of them if it is empty
+func printImageList(ctx *cli.Context, c *harvclient.Clientset, namespace string) (err error) {
+	imgList, err := c.HarvesterhciV1beta1().VirtualMachineImages(namespace).List(context.TODO(), listOptions(ctx))
 
 	if err != nil {
 		return
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriter(ctx, withNamespaceColumn(ctx, [][]string{
 		{"NAME", "Name"},
 		{"ID", "Id"},
 		{"SOURCE TYPE", "SourceType"},
 		{"URL", "Url"},
-	},
-		ctxv1)
-
-	defer writer.Close()
+	}, "VirtualMachineImage.Namespace"),
+		[]string{"SIZE", "Size"})
 
 	for _, imgItem := range imgList.Items {
 
 		writer.Write(&ImageData{
-			Name:       imgItem.Spec.DisplayName,
-			Id:         imgItem.Name,
-			SourceType: imgItem.Spec.SourceType,
-			Url:        imgItem.Spec.URL,
+			Name:                imgItem.Spec.DisplayName,
+			Id:                  imgItem.Name,
+			SourceType:          imgItem.Spec.SourceType,
+			Url:                 imgItem.Spec.URL,
+			Size:                humanBytes(imgItem.Status.Size),
+			VirtualMachineImage: imgItem,
 		})
 
 	}
 
-	return writer.Err()
+	return writer.Close()
 }
 
 // imageCreate create a VM Image in Harvester based on a URL and a display name as well as an optional description
@@ -202,82 +396,41 @@ func imageCreate(ctx *cli.Context) (err error) {
 			}
 			logrus.Info("Successfully computed URL and credentials to Harvester!")
 
-			var fileReader io.Reader
-			fileReader, err = os.Open(source)
-			if err != nil {
-				return
-			}
-
-			var req *http.Request
-			multipartBody := &bytes.Buffer{}
-			writer := multipart.NewWriter(multipartBody)
-			var part io.Writer
-			part, err = writer.CreateFormFile("chunk", filepath.Base(source))
-
-			if err != nil {
-				return
-			}
-
-			_, err = io.Copy(part, fileReader)
-			logrus.Info("Successfully preparated file for upload!")
-			if err != nil {
-				return
+			var chunkSize k8sresource.Quantity
+			chunkSize, err = k8sresource.ParseQuantity(ctx.String("chunk-size"))
+			if err != nil || chunkSize.Value() <= 0 {
+				return fmt.Errorf("invalid chunk size %s, it should be a positive quantity such as 4Mi", ctx.String("chunk-size"))
 			}
 
-			err = writer.Close()
+			var httpClient *http.Client
+			httpClient, err = newHarvesterHTTPClient(rancherServerConfig)
 			if err != nil {
 				return
 			}
 
 			var vmImageCreateName string
-			vmImageCreateName, err = createImageObjectInAPI(ctx, vmImageDisplayName, sourceType, source)
+			vmImageCreateName, err = createImageObjectInAPI(ctx, vmImageDisplayName, sourceType, source, "")
 			if err != nil {
 				return
 			}
 			logrus.Info("Image Object successfully created in Kubernetes API!")
-			urlToSendFile := harvesterURL + "/v1/harvester/harvesterhci.io.virtualmachineimages/" + ctx.String("namespace") + "/" + vmImageCreateName + "?action=upload&size=" + strconv.FormatInt(filesize, 10)
-
-			req, err = http.NewRequest("POST", urlToSendFile, multipartBody)
-			if err != nil {
-				return
-			}
-			var rootCAs *x509.CertPool
-			rootCAs, err = x509.SystemCertPool()
-			if err != nil {
-				return err
-			}
-			pemBlock, _ := pem.Decode([]byte(rancherServerConfig.CACerts))
-			var ownCert *x509.Certificate
-			ownCert, err = x509.ParseCertificate(pemBlock.Bytes)
-			if err != nil {
-				return fmt.Errorf("invalid CA certification in Rancher configuration, %w", err)
-			}
-
-			rootCAs.AddCert(ownCert)
-
-			req.Header.Add("Authorization", "Bearer "+rancherServerConfig.TokenKey)
-			req.Header.Set("Content-Type", writer.FormDataContentType())
-			tr := &http.Transport{
-				TLSClientConfig: &tls.Config{
-					RootCAs: rootCAs,
-				},
-			}
-			httpClient := &http.Client{Transport: tr}
+			urlToSendFile := imageAPIURL(harvesterURL, ctx.String("namespace"), vmImageCreateName) + "?action=upload&size=" + strconv.FormatInt(filesize, 10)
 
 			logrus.Info("Uploading image file ...")
-			var resp *http.Response
-			resp, err = httpClient.Do(req)
-
+			err = uploadImageFile(httpClient, imageUploadOptions{
+				URL:       urlToSendFile,
+				Token:     rancherServerConfig.TokenKey,
+				FilePath:  source,
+				ChunkSize: int(chunkSize.Value()),
+				Retries:   ctx.Int("retries"),
+				Progress:  os.Stderr,
+			})
 			if err != nil {
 				return err
 			}
 
-			if resp.Status == "200 OK" {
-				logrus.Info("Successfully uploaded the image file! DONE!")
-				return nil
-			} else {
-				return fmt.Errorf("uploading image file to harvester was not successful: %s", resp.Body)
-			}
+			logrus.Info("Successfully uploaded the image file! DONE!")
+			return nil
 
 		} else {
 
@@ -287,13 +440,337 @@ func imageCreate(ctx *cli.Context) (err error) {
 		}
 
 	}
-	_, err = createImageObjectInAPI(ctx, vmImageDisplayName, sourceType, source)
+	_, err = createImageObjectInAPI(ctx, vmImageDisplayName, sourceType, source, "")
 
 	return
 
 }
 
-func createImageObjectInAPI(ctx *cli.Context, vmImageDisplayName string, sourceType string, source string) (vmImageCreateName string, err error) {
+// imageDownload implements the `image download` command, it streams the content of a VM image from the Harvester API to a local file
+func imageDownload(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the image ID")
+	}
+
+	imageNS, imageName, err := getNamespaceAndName(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmImage, err := c.HarvesterhciV1beta1().VirtualMachineImages(imageNS).Get(context.TODO(), imageName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error during getting image object, %w", err)
+	}
+
+	rancherServerConfig, harvesterURL, err := getHarvesterAPIFromConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := newHarvesterHTTPClient(rancherServerConfig)
+	if err != nil {
+		return err
+	}
+
+	output := ctx.String("output")
+	if output == "" {
+		output = vmImage.Spec.DisplayName
+	}
+
+	downloadPath := output
+	if ctx.Bool("decompress") {
+		downloadPath = output + ".gz"
+	}
+
+	logrus.Infof("Downloading image %s/%s to %s ...", imageNS, imageName, downloadPath)
+	err = downloadImageFile(httpClient, imageDownloadOptions{
+		URL:      imageAPIURL(harvesterURL, imageNS, imageName) + "?link=download",
+		Token:    rancherServerConfig.TokenKey,
+		FilePath: downloadPath,
+		Resume:   ctx.Bool("resume"),
+		Progress: os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("decompress") {
+		logrus.Infof("Decompressing %s to %s ...", downloadPath, output)
+		err = decompressImageFile(downloadPath, output)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("Successfully downloaded image %s/%s to %s", imageNS, imageName, output)
+	return nil
+}
+
+// imageAPIURL returns the URL of a VM image in the Harvester API, on which upload and download actions can be called
+func imageAPIURL(harvesterURL string, namespace string, name string) string {
+	return harvesterURL + "/v1/harvester/harvesterhci.io.virtualmachineimages/" + namespace + "/" + name
+}
+
+// imageDownloadOptions holds the parameters of a streamed download of a VM image to a local file
+type imageDownloadOptions struct {
+	URL      string
+	Token    string
+	FilePath string
+	Resume   bool
+	Progress io.Writer
+}
+
+// downloadImageFile streams a VM image from Harvester to a local file.
+// When resuming, the bytes already present in the file are skipped using an HTTP Range request, if the server ignores the range the download starts over.
+func downloadImageFile(httpClient *http.Client, opts imageDownloadOptions) error {
+	var offset int64
+	if opts.Resume {
+		if fileInf, err := os.Stat(opts.FilePath); err == nil {
+			offset = fileInf.Size()
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, opts.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", "Bearer "+opts.Token)
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("downloading image file from harvester failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fileFlags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		logrus.Infof("Resuming download after %s", humanBytes(offset))
+		fileFlags |= os.O_APPEND
+	case http.StatusOK:
+		if offset > 0 {
+			logrus.Warn("The server does not support resuming downloads, starting over")
+			offset = 0
+		}
+		fileFlags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		size, ok := unsatisfiedRangeSize(resp.Header.Get("Content-Range"))
+		if !ok || size != offset {
+			return fmt.Errorf("file %s has %s and cannot be resumed, remove it or download without --resume", opts.FilePath, humanBytes(offset))
+		}
+		logrus.Infof("File %s is already complete", opts.FilePath)
+		return nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("downloading image file from harvester was not successful: %s %s", resp.Status, body)
+	}
+
+	file, err := os.OpenFile(opts.FilePath, fileFlags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var total int64
+	if resp.ContentLength > 0 {
+		total = offset + resp.ContentLength
+	}
+
+	progress := newProgressReader(resp.Body, opts.Progress, "Downloading "+filepath.Base(opts.FilePath), offset, total)
+	_, err = io.Copy(file, progress)
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("download interrupted, use --resume to continue it: %w", err)
+	}
+
+	return nil
+}
+
+// unsatisfiedRangeSize returns the size of the file from the Content-Range header "bytes */SIZE" of a 416 response
+func unsatisfiedRangeSize(contentRange string) (int64, bool) {
+	sizeString, found := strings.CutPrefix(contentRange, "bytes */")
+	if !found {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(sizeString, 10, 64)
+	return size, err == nil
+}
+
+// decompressImageFile extracts the gzip archive downloaded from Harvester to the target path and removes the archive.
+// If the downloaded file is not compressed, it is simply renamed.
+func decompressImageFile(archivePath string, targetPath string) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	bufferedArchive := bufio.NewReader(archive)
+	magic, err := bufferedArchive.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		logrus.Warnf("File %s is not a gzip archive, keeping it as is", archivePath)
+		archive.Close()
+		return os.Rename(archivePath, targetPath)
+	}
+
+	gzipReader, err := gzip.NewReader(bufferedArchive)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, gzipReader)
+	if err != nil {
+		return fmt.Errorf("error during decompression of %s: %w", archivePath, err)
+	}
+
+	archive.Close()
+	return os.Remove(archivePath)
+}
+
+// imageUploadOptions holds the parameters of a streamed upload of a local image file to Harvester
+type imageUploadOptions struct {
+	URL       string
+	Token     string
+	FilePath  string
+	ChunkSize int
+	Retries   int
+	Progress  io.Writer
+}
+
+// uploadImageFile streams a local image file to the upload action of Harvester, reading it chunk by chunk instead of loading it in memory.
+// The upload action has no way to continue a partial upload, an interrupted upload is therefore retried by streaming the file again from the beginning.
+func uploadImageFile(httpClient *http.Client, opts imageUploadOptions) (err error) {
+	var retriable bool
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 {
+			logrus.Warnf("Upload attempt %d failed: %s, retrying ...", attempt, err)
+			time.Sleep(uploadRetryDelay * time.Duration(attempt))
+		}
+
+		retriable, err = uploadImageFileOnce(httpClient, opts)
+		if err == nil || !retriable {
+			return
+		}
+	}
+
+	return fmt.Errorf("uploading image file to harvester failed after %d attempts: %w", opts.Retries+1, err)
+}
+
+// uploadImageFileOnce does a single upload attempt, it returns whether the error is worth retrying
+func uploadImageFileOnce(httpClient *http.Client, opts imageUploadOptions) (retriable bool, err error) {
+	file, err := os.Open(opts.FilePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	fileInf, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	fileName := filepath.Base(opts.FilePath)
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	contentLength, err := multipartFileLength(boundary, fileName, fileInf.Size())
+	if err != nil {
+		return false, err
+	}
+
+	bodyReader, bodyWriter := io.Pipe()
+	writer := multipart.NewWriter(bodyWriter)
+	err = writer.SetBoundary(boundary)
+	if err != nil {
+		return false, err
+	}
+
+	progress := newProgressReader(file, opts.Progress, "Uploading "+fileName, 0, fileInf.Size())
+
+	go func() {
+		part, err := writer.CreateFormFile("chunk", fileName)
+		if err == nil {
+			_, err = io.CopyBuffer(part, progress, make([]byte, opts.ChunkSize))
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		bodyWriter.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, opts.URL, bodyReader)
+	if err != nil {
+		bodyReader.Close()
+		return false, err
+	}
+	req.ContentLength = contentLength
+	req.Header.Add("Authorization", "Bearer "+opts.Token)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := httpClient.Do(req)
+	progress.Done()
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("uploading image file to harvester was not successful: %s %s", resp.Status, body)
+	}
+
+	return false, nil
+}
+
+// multipartFileLength computes the size of a multipart body holding a single file, so that the request can announce its length without being buffered
+func multipartFileLength(boundary string, fileName string, fileSize int64) (int64, error) {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	err := writer.SetBoundary(boundary)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = writer.CreateFormFile("chunk", fileName)
+	if err != nil {
+		return 0, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.n + fileSize, nil
+}
+
+// countingWriter discards its input and counts the number of bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+func createImageObjectInAPI(ctx *cli.Context, vmImageDisplayName string, sourceType string, source string, checksum string) (vmImageCreateName string, err error) {
 
 	if sourceType == "upload" {
 		source = ""
@@ -308,6 +785,7 @@ func createImageObjectInAPI(ctx *cli.Context, vmImageDisplayName string, sourceT
 			DisplayName: vmImageDisplayName,
 			SourceType:  sourceType,
 			URL:         source,
+			Checksum:    checksum,
 		},
 	}
 
@@ -364,36 +842,20 @@ func getHarvesterAPIFromConfig(ctx *cli.Context) (serverConfig *config.ServerCon
 
 func imageCatalog(ctx *cli.Context) (err error) {
 
-	metadataUrl := ctx.String("metadata-url")
-
-	logrus.Debug("current metadata url: " + metadataUrl)
-
-	var resp *http.Response
-	resp, err = http.Get(metadataUrl)
-	if err != nil {
-		return
-	}
-
-	defer resp.Body.Close()
-	var body []byte
-	body, err = io.ReadAll(resp.Body)
+	catalog, err := fetchCatalogFromContext(ctx)
 	if err != nil {
 		return
 	}
 
-	var catalog Catalog
-	err = json.Unmarshal(body, &catalog)
-
-	if err != nil {
-		return
+	if catalogSelectorsSet(ctx) {
+		return imageCatalogFromSelectors(ctx, catalog)
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriterWithOptions(os.Stdout, outputOptions{format: outputTable}, [][]string{
 		{"NUMBER", "Id"},
 		{"NAME", "Name"},
 		{"NUMBER OF IMAGES", "NumberOfImages"},
-	},
-		ctxv1)
+	})
 
 	osChoiceMap := make(map[int64]string)
 	var i int64 = 0
@@ -423,20 +885,20 @@ func imageCatalog(ctx *cli.Context) (err error) {
 
 	fmt.Printf("\nHere are the images available for %s\n\n", osSelection)
 
-	writer = rcmd.NewTableWriter([][]string{
+	writer = newTableWriterWithOptions(os.Stdout, outputOptions{format: outputTable}, [][]string{
 		{"NUMBER", "Id"},
 		{"NAME", "ShortName"},
 		{"VERSION", "Version"},
 		{"BUILD", "Build"},
 		{"URL", "Url"},
-	}, ctxv1)
+	})
 
-	imageChoiceMap := make(map[int64]string)
+	imageChoiceMap := make(map[int64]CatalogEntry)
 
 	for i, catalogItem := range catalog.HarvesterImageCatalog[osSelection] {
 		catalogItem.Id = int64(i) + 1
 		writer.Write(catalogItem)
-		imageChoiceMap[catalogItem.Id] = catalogItem.Url
+		imageChoiceMap[catalogItem.Id] = catalogItem
 	}
 
 	writer.Close()
@@ -447,7 +909,32 @@ func imageCatalog(ctx *cli.Context) (err error) {
 		return err
 	}
 
-	imageUrl := imageChoiceMap[int64(selection)]
+	return createImageFromCatalogEntry(ctx, imageChoiceMap[int64(selection)])
+}
+
+// imageCatalogFromSelectors creates an image from the single catalog entry matching the --os, --version and --build flags, without prompting
+func imageCatalogFromSelectors(ctx *cli.Context, catalog *Catalog) error {
+	filteredCatalog := filterCatalog(catalog, ctx.String("os"), ctx.String("version"), ctx.String("build"))
+	entries := catalogEntries(filteredCatalog)
+
+	if len(entries) == 0 {
+		return fmt.Errorf("no image in the catalog matches the given selectors")
+	}
+
+	if len(entries) > 1 {
+		err := printCatalogTable(ctx, entries)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%d images in the catalog match the given selectors, please refine them using --os, --version and --build", len(entries))
+	}
+
+	return createImageFromCatalogEntry(ctx, entries[0].CatalogEntry)
+}
+
+// createImageFromCatalogEntry creates a VM image in Harvester downloading from the URL of a catalog entry
+func createImageFromCatalogEntry(ctx *cli.Context, entry CatalogEntry) error {
+	imageUrl := entry.Url
 	fmt.Printf("\nYour image URL is : %s\n", imageUrl)
 	imageUrlObject, err := url.Parse(imageUrl)
 
@@ -457,8 +944,11 @@ func imageCatalog(ctx *cli.Context) (err error) {
 
 	urlPathComponents := strings.Split(imageUrlObject.EscapedPath(), "/")
 	imageFilename := urlPathComponents[len(urlPathComponents)-1]
+	if ctx.String("display-name") != "" {
+		imageFilename = ctx.String("display-name")
+	}
 
-	imageCreatedName, err := createImageObjectInAPI(ctx, imageFilename, "download", imageUrl)
+	imageCreatedName, err := createImageObjectInAPI(ctx, imageFilename, "download", imageUrl, catalogEntryChecksum(entry))
 	if err != nil {
 		return fmt.Errorf("error during creation of image in Harvester %w", err)
 	}
@@ -467,3 +957,372 @@ func imageCatalog(ctx *cli.Context) (err error) {
 
 	return nil
 }
+
+// imageCatalogList implements the `image catalog list` command, it prints the catalog entries matching the selectors without creating anything
+func imageCatalogList(ctx *cli.Context) error {
+	catalog, err := fetchCatalogFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	filteredCatalog := filterCatalog(catalog, ctx.String("os"), ctx.String("version"), ctx.String("build"))
+
+	return printCatalogTable(ctx, catalogEntries(filteredCatalog))
+}
+
+// catalogSelectorsSet returns true if any of the flags used to pick a catalog entry without prompting is given
+func catalogSelectorsSet(ctx *cli.Context) bool {
+	return ctx.String("os") != "" || ctx.String("version") != "" || ctx.String("build") != ""
+}
+
+// filterCatalog returns a catalog restricted to the entries matching the selectors, an empty selector matches everything.
+// The OS selector is case insensitive and also matches OS names it is a prefix of, so that "leap" matches both "leap" and "leap15.4+".
+func filterCatalog(catalog *Catalog, osSelector string, version string, build string) *Catalog {
+	filteredCatalog := &Catalog{
+		HarvesterImageCatalog: map[string][]CatalogEntry{},
+	}
+
+	for osName, entries := range catalog.HarvesterImageCatalog {
+		if !strings.HasPrefix(strings.ToLower(osName), strings.ToLower(osSelector)) {
+			continue
+		}
+
+		for _, entry := range entries {
+			if version != "" && entry.Version != version {
+				continue
+			}
+			if build != "" && !strings.EqualFold(entry.Build, build) {
+				continue
+			}
+			filteredCatalog.HarvesterImageCatalog[osName] = append(filteredCatalog.HarvesterImageCatalog[osName], entry)
+		}
+	}
+
+	return filteredCatalog
+}
+
+// CatalogImageData is a Data Structure that holds a catalog entry together with its OS, for display purposes
+type CatalogImageData struct {
+	Os string
+	CatalogEntry
+}
+
+// catalogEntries flattens a catalog into a list of entries sorted by OS
+func catalogEntries(catalog *Catalog) []CatalogImageData {
+	osNames := make([]string, 0, len(catalog.HarvesterImageCatalog))
+	for osName := range catalog.HarvesterImageCatalog {
+		osNames = append(osNames, osName)
+	}
+	sort.Strings(osNames)
+
+	var result []CatalogImageData
+	for _, osName := range osNames {
+		for _, entry := range catalog.HarvesterImageCatalog[osName] {
+			result = append(result, CatalogImageData{
+				Os:           osName,
+				CatalogEntry: entry,
+			})
+		}
+	}
+
+	return result
+}
+
+// printCatalogTable prints catalog entries as a table
+func printCatalogTable(ctx *cli.Context, entries []CatalogImageData) error {
+	writer := newTableWriter(ctx, [][]string{
+		{"OS", "Os"},
+		{"NAME", "ShortName"},
+		{"VERSION", "Version"},
+		{"BUILD", "Build"},
+		{"URL", "Url"},
+	})
+
+	for _, entry := range entries {
+		writer.Write(entry)
+	}
+
+	return writer.Close()
+}
+
+// imageShow implements the `image show` command, it prints the spec and the status of a VM image in YAML format
+func imageShow(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the image ID")
+	}
+
+	imageNS, imageName, err := getNamespaceAndName(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmImage, err := c.HarvesterhciV1beta1().VirtualMachineImages(imageNS).Get(context.TODO(), imageName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error during getting image object, %w", err)
+	}
+
+	if printed, err := printObject(ctx, vmImage); printed || err != nil {
+		return err
+	}
+
+	imageYAMLbytes, err := yaml.Marshal(buildImageDetails(vmImage))
+	if err != nil {
+		return fmt.Errorf("failed during encoding an object to YAML: %w", err)
+	}
+
+	fmt.Println(string(imageYAMLbytes))
+	return nil
+}
+
+// buildImageDetails maps a VirtualMachineImage object to the ImageDetails display structure
+func buildImageDetails(vmImage *v1beta1.VirtualMachineImage) *ImageDetails {
+	details := &ImageDetails{
+		Id:           vmImage.Name,
+		Namespace:    vmImage.Namespace,
+		DisplayName:  vmImage.Spec.DisplayName,
+		Description:  vmImage.Spec.Description,
+		Labels:       vmImage.Labels,
+		SourceType:   vmImage.Spec.SourceType,
+		Url:          vmImage.Spec.URL,
+		Checksum:     vmImage.Spec.Checksum,
+		Progress:     vmImage.Status.Progress,
+		Size:         humanBytes(vmImage.Status.Size),
+		StorageClass: vmImage.Status.StorageClassName,
+	}
+
+	for _, condition := range vmImage.Status.Conditions {
+		details.Conditions = append(details.Conditions, ImageCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime,
+		})
+	}
+
+	return details
+}
+
+// imageUpdate implements the `image update` command, it changes the display name, the description and the labels of a VM image
+func imageUpdate(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the image ID")
+	}
+
+	if !ctx.IsSet("display-name") && !ctx.IsSet("description") && !ctx.IsSet("label") {
+		return fmt.Errorf("nothing to update, please provide at least one of the flags --display-name, --description or --label")
+	}
+
+	imageNS, imageName, err := getNamespaceAndName(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmImage, err := c.HarvesterhciV1beta1().VirtualMachineImages(imageNS).Get(context.TODO(), imageName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error during getting image object, %w", err)
+	}
+
+	if ctx.IsSet("display-name") {
+		vmImage.Spec.DisplayName = ctx.String("display-name")
+	}
+
+	if ctx.IsSet("description") {
+		vmImage.Spec.Description = ctx.String("description")
+	}
+
+	vmImage.Labels, err = applyLabelChanges(vmImage.Labels, ctx.StringSlice("label"))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.HarvesterhciV1beta1().VirtualMachineImages(imageNS).Update(context.TODO(), vmImage, k8smetav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error during update of image %s: %w", imageName, err)
+	}
+
+	logrus.Infof("Image %s updated successfully", imageName)
+	return nil
+}
+
+// applyLabelChanges applies changes in the format <key>=<value> or <key>- to a map of labels
+func applyLabelChanges(labels map[string]string, changes []string) (map[string]string, error) {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	for _, change := range changes {
+		if strings.HasSuffix(change, "-") && !strings.Contains(change, "=") {
+			delete(labels, strings.TrimSuffix(change, "-"))
+			continue
+		}
+
+		parts := strings.SplitN(change, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %s, must be <key>=<value> or <key>-", change)
+		}
+		labels[parts[0]] = parts[1]
+	}
+
+	return labels, nil
+}
+
+// imageDelete deletes the VM images which IDs are given as arguments, wildcards are supported
+func imageDelete(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("at least one image ID is required")
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, imageArg := range ctx.Args().Slice() {
+		if strings.Contains(imageArg, "*") || strings.Contains(imageArg, "?") {
+			matchingImages, err := buildImageListMatchingWildcard(c, ctx, imageArg)
+			if err != nil {
+				return err
+			}
+
+			for _, vmImage := range matchingImages {
+				err = deleteImage(c, vmImage.Namespace, vmImage.Name)
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			imageNS, imageName, err := getNamespaceAndName(ctx, imageArg)
+			if err != nil {
+				return err
+			}
+
+			err = deleteImage(c, imageNS, imageName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildImageListMatchingWildcard creates an array of VM images which ID or display name match the given wildcard pattern
+func buildImageListMatchingWildcard(c *harvclient.Clientset, ctx *cli.Context, imageWildcard string) ([]v1beta1.VirtualMachineImage, error) {
+	images, err := c.HarvesterhciV1beta1().VirtualMachineImages(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error during listing of images: %w", err)
+	}
+
+	var matchingImages []v1beta1.VirtualMachineImage
+	for _, vmImage := range images.Items {
+		if wildcard.Match(imageWildcard, vmImage.Name) || wildcard.Match(imageWildcard, vmImage.Spec.DisplayName) {
+			matchingImages = append(matchingImages, vmImage)
+		}
+	}
+	logrus.Infof("number of matching images for pattern %s: %d", imageWildcard, len(matchingImages))
+	return matchingImages, nil
+}
+
+// deleteImage deletes a single VM image
+func deleteImage(c *harvclient.Clientset, namespace string, name string) error {
+	err := c.HarvesterhciV1beta1().VirtualMachineImages(namespace).Delete(context.TODO(), name, k8smetav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("image named %s could not be deleted successfully: %w", name, err)
+	}
+
+	logrus.Infof("Image %s deleted successfully", name)
+	return nil
+}
+
+// imageWait implements the `image wait` command, it watches a VM image until its Imported condition is either true or false
+func imageWait(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the image ID")
+	}
+
+	imageNS, imageName, err := getNamespaceAndName(ctx, ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), ctx.Duration("timeout"))
+	defer cancel()
+
+	watcher, err := c.HarvesterhciV1beta1().VirtualMachineImages(imageNS).Watch(timeoutCtx, k8smetav1.ListOptions{
+		FieldSelector: "metadata.name=" + imageName,
+	})
+	if err != nil {
+		return fmt.Errorf("error during watching image %s: %w", imageName, err)
+	}
+	defer watcher.Stop()
+
+	lastProgress := -1
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out after %s waiting for image %s to be imported", ctx.Duration("timeout"), imageName)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch on image %s was closed before the image was imported", imageName)
+			}
+
+			if event.Type == watch.Deleted {
+				return fmt.Errorf("image %s was deleted", imageName)
+			}
+
+			vmImage, ok := event.Object.(*v1beta1.VirtualMachineImage)
+			if !ok {
+				continue
+			}
+
+			if vmImage.Status.Progress != lastProgress {
+				lastProgress = vmImage.Status.Progress
+				logrus.Infof("Image %s import progress: %d%%", imageName, lastProgress)
+			}
+
+			imported, err := imageImportResult(vmImage)
+			if err != nil {
+				return err
+			}
+			if imported {
+				logrus.Infof("Image %s is imported", imageName)
+				return nil
+			}
+		}
+	}
+}
+
+// imageImportResult returns true if a VM image is imported, and an error if its import failed
+func imageImportResult(vmImage *v1beta1.VirtualMachineImage) (bool, error) {
+	for _, condition := range vmImage.Status.Conditions {
+		if condition.Type != v1beta1.ImageImported && condition.Type != v1beta1.ImageInitialized {
+			continue
+		}
+
+		if condition.Status == v1.ConditionFalse {
+			return false, fmt.Errorf("import of image %s failed: %s %s", vmImage.Name, condition.Reason, condition.Message)
+		}
+
+		if condition.Type == v1beta1.ImageImported && condition.Status == v1.ConditionTrue {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
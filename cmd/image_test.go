@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestUploadImageFile(t *testing.T) {
+	uploadRetryDelay = 0
+	content := bytes.Repeat([]byte("harvester-image-"), 4096)
+	source := filepath.Join(t.TempDir(), "image.qcow2")
+	if err := os.WriteFile(source, content, 0600); err != nil {
+		t.Fatalf("Error writing test image: %v", err)
+	}
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Query().Get("action") != "upload" {
+			t.Errorf("Expected upload action, got %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("Authorization") != "Bearer token-xyz" {
+			t.Errorf("Unexpected Authorization header %s", r.Header.Get("Authorization"))
+		}
+
+		file, header, err := r.FormFile("chunk")
+		if err != nil {
+			t.Errorf("Error reading multipart file: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		received, _ := io.ReadAll(file)
+		if header.Filename != "image.qcow2" || !bytes.Equal(received, content) {
+			t.Errorf("Received file %s of %d bytes, expected image.qcow2 of %d bytes", header.Filename, len(received), len(content))
+		}
+
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := uploadImageFile(server.Client(), imageUploadOptions{
+		URL:       server.URL + "/v1/harvester/harvesterhci.io.virtualmachineimages/default/image-abcde?action=upload&size=65536",
+		Token:     "token-xyz",
+		FilePath:  source,
+		ChunkSize: 1024,
+		Retries:   2,
+	})
+	if err != nil {
+		t.Errorf("Error uploading image: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 upload attempts, got %d", attempts)
+	}
+}
+
+func TestUploadImageFileNotRetriedOnClientError(t *testing.T) {
+	uploadRetryDelay = 0
+	source := filepath.Join(t.TempDir(), "image.img")
+	if err := os.WriteFile(source, []byte("image"), 0600); err != nil {
+		t.Fatalf("Error writing test image: %v", err)
+	}
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := uploadImageFile(server.Client(), imageUploadOptions{
+		URL:       server.URL,
+		FilePath:  source,
+		ChunkSize: 1024,
+		Retries:   3,
+	})
+	if err == nil {
+		t.Errorf("Expected an error for a forbidden upload")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 upload attempt, got %d", attempts)
+	}
+}
+
+func TestDownloadImageFileResume(t *testing.T) {
+	content := bytes.Repeat([]byte("golden-image-"), 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("link") != "download" {
+			t.Errorf("Expected download link, got %s", r.URL.RawQuery)
+		}
+		http.ServeContent(w, r, "image.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "image.gz")
+	if err := os.WriteFile(target, content[:1000], 0600); err != nil {
+		t.Fatalf("Error writing partial download: %v", err)
+	}
+
+	err := downloadImageFile(server.Client(), imageDownloadOptions{
+		URL:      server.URL + "?link=download",
+		FilePath: target,
+		Resume:   true,
+	})
+	if err != nil {
+		t.Errorf("Error downloading image: %v", err)
+	}
+
+	downloaded, _ := os.ReadFile(target)
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("Downloaded file has %d bytes, expected %d identical bytes", len(downloaded), len(content))
+	}
+}
+
+func TestDownloadImageFileResumeComplete(t *testing.T) {
+	content := bytes.Repeat([]byte("golden-image-"), 2048)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "image.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "image.gz")
+	if err := os.WriteFile(target, content, 0600); err != nil {
+		t.Fatalf("Error writing complete download: %v", err)
+	}
+
+	opts := imageDownloadOptions{URL: server.URL, FilePath: target, Resume: true}
+	err := downloadImageFile(server.Client(), opts)
+	if err != nil {
+		t.Errorf("Expected a complete file to be kept, got %v", err)
+	}
+
+	// a larger file from another image must not be reported as complete
+	if err := os.WriteFile(target, append(content, content...), 0600); err != nil {
+		t.Fatalf("Error writing larger file: %v", err)
+	}
+	err = downloadImageFile(server.Client(), opts)
+	if err == nil {
+		t.Errorf("Expected an error for a local file larger than the image")
+	}
+}
+
+func TestUnsatisfiedRangeSize(t *testing.T) {
+	size, ok := unsatisfiedRangeSize("bytes */26624")
+	if !ok || size != 26624 {
+		t.Errorf("Expected a size of 26624, got %d (%t)", size, ok)
+	}
+
+	for _, contentRange := range []string{"", "bytes 0-99/26624", "bytes */*"} {
+		if _, ok := unsatisfiedRangeSize(contentRange); ok {
+			t.Errorf("Expected no size for the Content-Range %q", contentRange)
+		}
+	}
+}
+
+func TestImageImportResult(t *testing.T) {
+	vmImage := &v1beta1.VirtualMachineImage{}
+	vmImage.Status.Conditions = []v1beta1.Condition{
+		{Type: v1beta1.ImageInitialized, Status: corev1.ConditionTrue},
+		{Type: v1beta1.ImageImported, Status: corev1.ConditionUnknown},
+	}
+
+	imported, err := imageImportResult(vmImage)
+	if imported || err != nil {
+		t.Errorf("Expected an image in progress, got imported=%v err=%v", imported, err)
+	}
+
+	vmImage.Status.Conditions[1].Status = corev1.ConditionTrue
+	imported, err = imageImportResult(vmImage)
+	if !imported || err != nil {
+		t.Errorf("Expected an imported image, got imported=%v err=%v", imported, err)
+	}
+
+	vmImage.Status.Conditions[1].Status = corev1.ConditionFalse
+	vmImage.Status.Conditions[1].Reason = "UploadFailed"
+	_, err = imageImportResult(vmImage)
+	if err == nil {
+		t.Errorf("Expected an error for a failed import")
+	}
+}
+
+func TestFilterCatalog(t *testing.T) {
+	catalog := &Catalog{
+		HarvesterImageCatalog: map[string][]CatalogEntry{
+			"leap": {
+				{ShortName: "OpenSUSE Leap JeOS 15.3", Version: "15.3", Build: "current"},
+			},
+			"leap15.4+": {
+				{ShortName: "OpenSUSE Leap Minimal 15.4", Version: "15.4", Build: "current"},
+				{ShortName: "OpenSUSE Leap Minimal 15.5", Version: "15.5", Build: "current"},
+			},
+			"ubuntu": {
+				{ShortName: "Ubuntu Jammy", Version: "22.04", Build: "current"},
+			},
+		},
+	}
+
+	entries := catalogEntries(filterCatalog(catalog, "leap", "15.4", "Current"))
+	if len(entries) != 1 || entries[0].Os != "leap15.4+" || entries[0].Version != "15.4" {
+		t.Errorf("Expected only the leap 15.4 entry, got %v", entries)
+	}
+
+	entries = catalogEntries(filterCatalog(catalog, "", "", "current"))
+	if len(entries) != 4 {
+		t.Errorf("Expected 4 entries, got %d", len(entries))
+	}
+	if entries[0].Os != "leap" || entries[3].Os != "ubuntu" {
+		t.Errorf("Expected entries sorted by OS, got %v", entries)
+	}
+}
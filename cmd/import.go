@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"strings"
 
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
 	VMImportV1 "github.com/harvester/vm-import-controller/pkg/apis/migration.harvesterhci.io/v1beta1"
-	rcmd "github.com/rancher/cli/cmd"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 )
 
 var (
@@ -20,11 +23,17 @@ var (
 )
 
 type VMImportData struct {
-	Name          string
-	VMName        string
-	SourceCluster string
-	ClusterType   string
-	Status        string
+	Name                 string
+	VMName               string
+	SourceCluster        string
+	ClusterType          string
+	Status               string
+	VirtualMachineImport VMImportV1.VirtualMachineImport
+}
+
+// APIObject returns the VM import the display structure was built from
+func (d VMImportData) APIObject() interface{} {
+	return d.VirtualMachineImport
 }
 
 // Manages VM imports using vm-import-controller of Harvester
@@ -56,6 +65,11 @@ func importListCommand() *cli.Command {
 		Name:   "list",
 		Usage:  "List VM imports",
 		Action: listVMImports,
+		Flags: []cli.Flag{
+			&watchFlag,
+			&outputFlag,
+			&noHeadersFlag,
+		},
 	}
 }
 
@@ -219,6 +233,33 @@ func listVMImports(ctx *cli.Context) error {
 		return err
 	}
 
+	if !ctx.Bool("watch") {
+		return printVMImportList(ctx, c)
+	}
+
+	// VM imports are not part of the Harvester clientset, so they are watched through the dynamic client
+	restConfig, err := GetRESTClientAndConfig(ctx)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	gv, err := schema.ParseGroupVersion(harvesterMigrationAPIGroup)
+	if err != nil {
+		return err
+	}
+
+	return renderOnChanges(func() error {
+		return printVMImportList(ctx, c)
+	}, func(watchCtx context.Context) (watch.Interface, error) {
+		return dynamicClient.Resource(gv.WithResource("virtualmachineimports")).Namespace("harvester-system").Watch(watchCtx, v1.ListOptions{})
+	})
+}
+
+// printVMImportList prints the VM imports
+func printVMImportList(ctx *cli.Context, c *harvclient.Clientset) error {
 	vmImportResultRaw, err := c.HarvesterhciV1beta1().RESTClient().Get().Resource("virtualmachineimports.migration").Namespace("harvester-system").DoRaw(context.Background())
 
 	if err != nil {
@@ -232,27 +273,26 @@ func listVMImports(ctx *cli.Context) error {
 		return fmt.Errorf("failed to unmarshal VM import list: %v", err)
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriter(ctx, [][]string{
 		{"NAME", "Name"},
 		{"VM NAME", "VMName"},
 		{"STATUS", "Status"},
 		{"SOURCE_CLUSTER", "SourceCluster"},
 		{"CLUSTER_TYPE", "ClusterType"},
-	}, ctxv1)
-
-	defer writer.Close()
+	})
 
 	for _, vmImport := range vmImportList.Items {
 		writer.Write(&VMImportData{
-			Name:          vmImport.Name,
-			VMName:        vmImport.Spec.VirtualMachineName,
-			Status:        string(vmImport.Status.Status),
-			SourceCluster: vmImport.Spec.SourceCluster.Name,
-			ClusterType:   vmImport.Spec.SourceCluster.Kind,
+			Name:                 vmImport.Name,
+			VMName:               vmImport.Spec.VirtualMachineName,
+			Status:               string(vmImport.Status.Status),
+			SourceCluster:        vmImport.Spec.SourceCluster.Name,
+			ClusterType:          vmImport.Spec.SourceCluster.Kind,
+			VirtualMachineImport: vmImport,
 		})
 	}
 
-	return writer.Err()
+	return writer.Close()
 }
 
 func configureVMImport(ctx *cli.Context) error {
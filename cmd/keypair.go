@@ -4,15 +4,20 @@ import (
 	"context"
 	"time"
 
-	rcmd "github.com/rancher/cli/cmd"
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/urfave/cli/v2"
-	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type KeypairData struct {
 	Name              string
 	Fingerprint       string
 	CreationTimestamp string
+	KeyPair           v1beta1.KeyPair
+}
+
+// APIObject returns the keypair the display structure was built from
+func (d KeypairData) APIObject() interface{} {
+	return d.KeyPair
 }
 
 // TemplateCommand defines the CLI command that lists VM templates in Harvester
@@ -22,9 +27,11 @@ func KeypairCommand() *cli.Command {
 		Aliases: []string{"key", "ssh-key"},
 		Usage:   "Manipulate SSH Keypairs",
 		Action:  keypairList,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&nsFlag,
-		},
+			&outputFlag,
+			&noHeadersFlag,
+		}, listFlags()...),
 		Subcommands: cli.Commands{
 			&cli.Command{
 				Name:        "list",
@@ -33,9 +40,11 @@ func KeypairCommand() *cli.Command {
 				Description: "\nLists all the SSH Keypairs available in Harvester",
 				ArgsUsage:   "None",
 				Action:      keypairList,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+					&outputFlag,
+					&noHeadersFlag,
+				}, listFlags()...),
 			},
 		},
 	}
@@ -48,20 +57,17 @@ func keypairList(ctx *cli.Context) (err error) {
 		return
 	}
 
-	keyList, err := c.HarvesterhciV1beta1().KeyPairs(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	keyList, err := c.HarvesterhciV1beta1().KeyPairs(listNamespace(ctx)).List(context.TODO(), listOptions(ctx))
 
 	if err != nil {
 		return
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriter(ctx, withNamespaceColumn(ctx, [][]string{
 		{"NAME", "Name"},
 		{"FINGERPRINT", "Fingerprint"},
 		{"CREATION TIMESTAMP", "CreationTimestamp"},
-	},
-		ctxv1)
-
-	defer writer.Close()
+	}, "KeyPair.Namespace"))
 
 	for _, keyItem := range keyList.Items {
 
@@ -69,9 +75,10 @@ func keypairList(ctx *cli.Context) (err error) {
 			Name:              keyItem.Name,
 			Fingerprint:       keyItem.Status.FingerPrint,
 			CreationTimestamp: keyItem.CreationTimestamp.Format(time.RFC822),
+			KeyPair:           keyItem,
 		})
 
 	}
 
-	return writer.Err()
+	return writer.Close()
 }
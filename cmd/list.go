@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	selectorFlag = cli.StringFlag{
+		Name:    "selector",
+		Aliases: []string{"l"},
+		Usage:   "Label selector of the objects, e.g. app=web",
+	}
+	fieldSelectorFlag = cli.StringFlag{
+		Name:  "field-selector",
+		Usage: "Field selector of the objects, e.g. metadata.name=vm1",
+	}
+	allNamespacesFlag = cli.BoolFlag{
+		Name:    "all-namespaces",
+		Aliases: []string{"A"},
+		Usage:   "Use the objects of all the namespaces, the namespace flag is ignored",
+	}
+	sortByFlag = cli.StringFlag{
+		Name:  "sort-by",
+		Usage: "Column by which the list is sorted, given by its header, e.g. name or \"creation timestamp\"",
+	}
+)
+
+// listFlags returns the flags selecting and sorting the objects printed by the list commands
+func listFlags() []cli.Flag {
+	return []cli.Flag{&selectorFlag, &fieldSelectorFlag, &allNamespacesFlag, &sortByFlag}
+}
+
+// listNamespace returns the namespace in which the objects are listed, which is empty for all the namespaces
+func listNamespace(ctx *cli.Context) string {
+	if ctx.Bool(allNamespacesFlag.Name) {
+		return k8smetav1.NamespaceAll
+	}
+	return ctx.String("namespace")
+}
+
+// listOptions returns the list options holding the label and field selectors given on the command line
+func listOptions(ctx *cli.Context) k8smetav1.ListOptions {
+	return k8smetav1.ListOptions{
+		LabelSelector: ctx.String(selectorFlag.Name),
+		FieldSelector: ctx.String(fieldSelectorFlag.Name),
+	}
+}
+
+// withNamespaceColumn adds a NAMESPACE column in front of the columns of a table when the objects of all the namespaces are listed
+func withNamespaceColumn(ctx *cli.Context, columns [][]string, namespaceField string) [][]string {
+	if !ctx.Bool(allNamespacesFlag.Name) {
+		return columns
+	}
+	return append([][]string{{"NAMESPACE", namespaceField}}, columns...)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestListFlags(t *testing.T) {
+	tests := []struct {
+		args              []string
+		expectedNamespace string
+		expectedOptions   k8smetav1.ListOptions
+		expectedColumns   [][]string
+	}{
+		{
+			args:              []string{"harvester", "list", "--namespace", "tenant1"},
+			expectedNamespace: "tenant1",
+			expectedColumns:   [][]string{{"NAME", "Name"}},
+		},
+		{
+			args:              []string{"harvester", "list", "-A", "-l", "env=staging", "--field-selector", "status.printableStatus=Running"},
+			expectedNamespace: k8smetav1.NamespaceAll,
+			expectedOptions:   k8smetav1.ListOptions{LabelSelector: "env=staging", FieldSelector: "status.printableStatus=Running"},
+			expectedColumns:   [][]string{{"NAMESPACE", "VirtualMachine.Namespace"}, {"NAME", "Name"}},
+		},
+	}
+
+	for _, test := range tests {
+		var namespace string
+		var options k8smetav1.ListOptions
+		var columns [][]string
+		app := &cli.App{
+			Commands: []*cli.Command{
+				{
+					Name:  "list",
+					Flags: append([]cli.Flag{&nsFlag}, listFlags()...),
+					Action: func(ctx *cli.Context) error {
+						namespace = listNamespace(ctx)
+						options = listOptions(ctx)
+						columns = withNamespaceColumn(ctx, [][]string{{"NAME", "Name"}}, "VirtualMachine.Namespace")
+						return nil
+					},
+				},
+			},
+		}
+
+		err := app.Run(test.args)
+		if err != nil {
+			t.Fatalf("Unexpected error when running %v: %v", test.args, err)
+		}
+		if namespace != test.expectedNamespace || options != test.expectedOptions || !reflect.DeepEqual(columns, test.expectedColumns) {
+			t.Errorf("Unexpected namespace %q, options %+v or columns %v for %v", namespace, options, columns, test.args)
+		}
+	}
+}
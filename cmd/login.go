@@ -17,7 +17,6 @@ import (
 
 	"github.com/grantae/certinfo"
 	"github.com/rancher/cli/cliclient"
-	rcmd "github.com/rancher/cli/cmd"
 	"github.com/rancher/cli/config"
 	managementClient "github.com/rancher/types/client/management/v3"
 	"github.com/urfave/cli/v2"
@@ -236,13 +235,13 @@ func getProjectContext(ctx *cli.Context, c *cliclient.MasterClient) (string, err
 		return "", err
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriterWithOptions(os.Stdout, outputOptions{format: outputTable}, [][]string{
 		{"NUMBER", "Index"},
 		{"CLUSTER NAME", "ClusterName"},
 		{"PROJECT ID", "Project.ID"},
 		{"PROJECT NAME", "Project.Name"},
 		{"PROJECT DESCRIPTION", "Project.Description"},
-	}, ctxv1)
+	})
 
 	for i, item := range projectCollection.Data {
 		writer.Write(&LoginData{
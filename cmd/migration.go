@@ -0,0 +1,467 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/minio/pkg/wildcard"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const defaultMigrationWaitTimeout = 30 * time.Minute
+
+// VMMigrationData is a Data Structure that holds information to display for VM live migrations
+type VMMigrationData struct {
+	Name                            string
+	VMName                          string
+	Phase                           string
+	SourceNode                      string
+	TargetNode                      string
+	CreationTimestamp               string
+	VirtualMachineInstanceMigration VMv1.VirtualMachineInstanceMigration
+}
+
+// APIObject returns the VM migration the display structure was built from
+func (d VMMigrationData) APIObject() interface{} {
+	return d.VirtualMachineInstanceMigration
+}
+
+// vmMigrateCommand defines the CLI sub-command `vm migrate` that live-migrates VMs to another node
+func vmMigrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "migrate",
+		Usage:       "Live-migrate VMs to another node",
+		Description: "\nLive-migrates the VMs given as arguments, which may contain wildcards, and follows the migrations until they succeed or fail",
+		ArgsUsage:   "VM_NAME...",
+		Action:      vmMigrate,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:  "target-node",
+				Usage: "Node to migrate the VMs to, defaults to a node chosen by the scheduler",
+			},
+			&cli.BoolFlag{
+				Name:  "cancel",
+				Usage: "Cancel the migrations in progress of the VMs instead of starting new ones",
+			},
+			&cli.BoolFlag{
+				Name:  "no-wait",
+				Usage: "Do not wait for the migrations to succeed or fail",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Maximum duration to wait for all the migrations to finish",
+				Value: defaultMigrationWaitTimeout,
+			},
+		},
+	}
+}
+
+// vmMigrationsCommand defines the CLI sub-command `vm migrations` that lists VM live migrations
+func vmMigrationsCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "migrations",
+		Usage:  "Manage VM live migrations",
+		Action: vmMigrationList,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&outputFlag,
+			&noHeadersFlag,
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:        "list",
+				Aliases:     []string{"ls"},
+				Usage:       "List VM live migrations",
+				Description: "\nLists the VM live migrations, optionally only the ones of the VMs matching the argument, which may contain wildcards",
+				ArgsUsage:   "[VM_NAME]",
+				Action:      vmMigrationList,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				},
+			},
+		},
+	}
+}
+
+// vmMigrate implements the `vm migrate` command
+func vmMigrate(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("at least one VM name is required")
+	}
+
+	if ctx.Bool("cancel") && ctx.String("target-node") != "" {
+		return fmt.Errorf("the flags --cancel and --target-node cannot be used together")
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	namespace := ctx.String("namespace")
+	vmNames, err := expandVMNames(c, namespace, ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	if ctx.Bool("cancel") {
+		for _, vmName := range vmNames {
+			err := cancelVMMigration(c, namespace, vmName)
+			if err != nil {
+				logrus.Warnf("Cancelling the migration of VM %s failed: %v", vmName, err)
+				failed = append(failed, vmName)
+			}
+		}
+		return migrationResult(failed)
+	}
+
+	migrations := map[string]string{}
+	for _, vmName := range vmNames {
+		migrationName, err := startVMMigration(ctx, c, namespace, vmName, ctx.String("target-node"))
+		if err != nil {
+			logrus.Warnf("Migrating VM %s failed: %v", vmName, err)
+			failed = append(failed, vmName)
+			continue
+		}
+		logrus.Infof("Migration %s of VM %s started", migrationName, vmName)
+		migrations[vmName] = migrationName
+	}
+
+	if ctx.Bool("no-wait") {
+		return migrationResult(failed)
+	}
+
+	failed = append(failed, waitForVMMigrations(vmNames, migrations, ctx.Duration("timeout"), func(migrationName string, timeout time.Duration) error {
+		return waitForVMMigration(c, namespace, migrationName, timeout)
+	})...)
+
+	return migrationResult(failed)
+}
+
+// waitForVMMigrations waits for the migrations of the VMs one after the other, all within the same timeout, and returns the VMs which migration failed
+func waitForVMMigrations(vmNames []string, migrations map[string]string, timeout time.Duration, wait func(migrationName string, timeout time.Duration) error) []string {
+	var failed []string
+	deadline := time.Now().Add(timeout)
+	for _, vmName := range vmNames {
+		migrationName, ok := migrations[vmName]
+		if !ok {
+			continue
+		}
+
+		err := wait(migrationName, time.Until(deadline))
+		if err != nil {
+			logrus.Warnf("Migrating VM %s failed: %v", vmName, err)
+			failed = append(failed, vmName)
+		}
+	}
+	return failed
+}
+
+// migrationResult returns an error listing the VMs for which the operation failed, if any
+func migrationResult(failed []string) error {
+	if len(failed) > 0 {
+		return fmt.Errorf("migration failed for VMs: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// expandVMNames replaces the VM names containing wildcards by the names of the matching VMs, keeping the other ones as they are
+func expandVMNames(c *harvclient.Clientset, namespace string, args []string) ([]string, error) {
+	var vmNames []string
+	for _, vmName := range args {
+		if strings.Contains(vmName, "*") || strings.Contains(vmName, "?") {
+			matchingVMs, err := listVMsMatchingWildcard(c, namespace, vmName)
+			if err != nil {
+				return nil, err
+			}
+			for _, vm := range matchingVMs {
+				vmNames = append(vmNames, vm.Name)
+			}
+		} else {
+			vmNames = append(vmNames, vmName)
+		}
+	}
+
+	if len(vmNames) == 0 {
+		return nil, fmt.Errorf("no VM matching %s found", strings.Join(args, ", "))
+	}
+	return vmNames, nil
+}
+
+// startVMMigration starts the live migration of a running VM and returns the name of the VirtualMachineInstanceMigration object
+func startVMMigration(ctx *cli.Context, c *harvclient.Clientset, namespace string, vmName string, targetNode string) (string, error) {
+	vmi, err := c.KubevirtV1().VirtualMachineInstances(namespace).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("VM %s is not running: %w", vmName, err)
+	}
+
+	if vmi.Status.Phase != VMv1.Running {
+		return "", fmt.Errorf("VM %s is not running, its phase is %s", vmName, vmi.Status.Phase)
+	}
+
+	if vmi.Status.MigrationState != nil && !vmi.Status.MigrationState.Completed && !vmi.Status.MigrationState.Failed {
+		return "", fmt.Errorf("VM %s is already migrating", vmName)
+	}
+
+	if targetNode == "" {
+		vmimCreated, err := c.KubevirtV1().VirtualMachineInstanceMigrations(namespace).Create(context.TODO(), buildVMMigration(namespace, vmName), k8smetav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("error during creation of the migration: %w", err)
+		}
+		return vmimCreated.Name, nil
+	}
+
+	if targetNode == vmi.Status.NodeName {
+		return "", fmt.Errorf("VM %s is already running on node %s", vmName, targetNode)
+	}
+
+	// Pinning the VMI to the target node is only allowed to the Harvester controller, so the migration is requested through the Harvester API
+	err = postVMAction(ctx, namespace, vmName, "migrate", vmMigrateActionInput(targetNode))
+	if err != nil {
+		return "", err
+	}
+
+	vmim, err := findActiveVMMigration(c, namespace, vmName)
+	if err != nil {
+		return "", err
+	}
+	if vmim == nil {
+		return "", fmt.Errorf("no migration found for VM %s after requesting it", vmName)
+	}
+	return vmim.Name, nil
+}
+
+// buildVMMigration creates the VirtualMachineInstanceMigration object which migrates a VM to a node chosen by the scheduler
+func buildVMMigration(namespace string, vmName string) *VMv1.VirtualMachineInstanceMigration {
+	return &VMv1.VirtualMachineInstanceMigration{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			GenerateName: vmName + "-",
+			Namespace:    namespace,
+		},
+		Spec: VMv1.VirtualMachineInstanceMigrationSpec{
+			VMIName: vmName,
+		},
+	}
+}
+
+// vmMigrateActionInput creates the input of the migrate action of the Harvester API, which migrates a VM to the given node
+func vmMigrateActionInput(targetNode string) map[string]string {
+	return map[string]string{"nodeName": targetNode}
+}
+
+// postVMAction calls an action of the Harvester API on a VM, with the given input encoded in JSON as the request body
+func postVMAction(ctx *cli.Context, namespace string, vmName string, action string, input interface{}) error {
+	rancherServerConfig, harvesterURL, err := getHarvesterAPIFromConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := newHarvesterHTTPClient(rancherServerConfig)
+	if err != nil {
+		return err
+	}
+
+	return postVMActionWithClient(httpClient, harvesterURL, rancherServerConfig.TokenKey, namespace, vmName, action, input)
+}
+
+// postVMActionWithClient calls an action of the Harvester API at harvesterURL on a VM, authenticating with the given token
+func postVMActionWithClient(httpClient *http.Client, harvesterURL string, token string, namespace string, vmName string, action string, input interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, harvesterURL+"/v1/harvester/kubevirt.io.virtualmachines/"+namespace+"/"+vmName+"?action="+action, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error during %s action on VM %s: %w", action, vmName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s action on VM %s was not successful: %s %s", action, vmName, resp.Status, respBody)
+	}
+
+	return nil
+}
+
+// findActiveVMMigration returns the most recent migration of a VM which has not finished yet, or nil if there is none
+func findActiveVMMigration(c *harvclient.Clientset, namespace string, vmName string) (*VMv1.VirtualMachineInstanceMigration, error) {
+	vmimList, err := c.KubevirtV1().VirtualMachineInstanceMigrations(namespace).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error during listing of migrations: %w", err)
+	}
+
+	return activeVMMigration(vmimList.Items, vmName), nil
+}
+
+// activeVMMigration returns the most recent migration of a VM among vmims which has not finished yet, or nil if there is none
+func activeVMMigration(vmims []VMv1.VirtualMachineInstanceMigration, vmName string) *VMv1.VirtualMachineInstanceMigration {
+	var active *VMv1.VirtualMachineInstanceMigration
+	for i, vmim := range vmims {
+		if vmim.Spec.VMIName != vmName || vmim.IsFinal() {
+			continue
+		}
+		if active == nil || active.CreationTimestamp.Before(&vmim.CreationTimestamp) {
+			active = &vmims[i]
+		}
+	}
+
+	return active
+}
+
+// cancelVMMigration cancels the migration in progress of a VM, by deleting its VirtualMachineInstanceMigration object
+func cancelVMMigration(c *harvclient.Clientset, namespace string, vmName string) error {
+	vmim, err := findActiveVMMigration(c, namespace, vmName)
+	if err != nil {
+		return err
+	}
+	if vmim == nil {
+		return fmt.Errorf("no migration in progress for VM %s", vmName)
+	}
+
+	err = c.KubevirtV1().VirtualMachineInstanceMigrations(namespace).Delete(context.TODO(), vmim.Name, k8smetav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("error during deletion of migration %s: %w", vmim.Name, err)
+	}
+
+	logrus.Infof("Migration %s of VM %s cancelled", vmim.Name, vmName)
+	return nil
+}
+
+// waitForVMMigration follows the phase of a migration until it succeeds or fails
+func waitForVMMigration(c *harvclient.Clientset, namespace string, name string, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	watcher, err := c.KubevirtV1().VirtualMachineInstanceMigrations(namespace).Watch(timeoutCtx, k8smetav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("error during watching %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	var lastPhase VMv1.VirtualMachineInstanceMigrationPhase
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out waiting for migration %s to finish", name)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch on %s was closed before it finished", name)
+			}
+
+			if event.Type == watch.Deleted {
+				return fmt.Errorf("migration %s was cancelled", name)
+			}
+
+			vmim, ok := event.Object.(*VMv1.VirtualMachineInstanceMigration)
+			if !ok {
+				continue
+			}
+
+			if vmim.Status.Phase != lastPhase {
+				lastPhase = vmim.Status.Phase
+				logrus.Infof("%s: phase %s", name, vmim.Status.Phase)
+			}
+
+			switch vmim.Status.Phase {
+			case VMv1.MigrationSucceeded:
+				return nil
+			case VMv1.MigrationFailed:
+				return fmt.Errorf("migration %s failed", name)
+			}
+		}
+	}
+}
+
+// vmMigrationList implements the `vm migrations list` command
+func vmMigrationList(ctx *cli.Context) error {
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	namespace := ctx.String("namespace")
+	vmimList, err := c.KubevirtV1().VirtualMachineInstanceMigrations(namespace).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	vmiList, err := c.KubevirtV1().VirtualMachineInstances(namespace).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	migrationStates := map[string]*VMv1.VirtualMachineInstanceMigrationState{}
+	for _, vmi := range vmiList.Items {
+		if vmi.Status.MigrationState != nil {
+			migrationStates[string(vmi.Status.MigrationState.MigrationUID)] = vmi.Status.MigrationState
+		}
+	}
+
+	sort.Slice(vmimList.Items, func(i, j int) bool {
+		return vmimList.Items[i].CreationTimestamp.Before(&vmimList.Items[j].CreationTimestamp)
+	})
+
+	writer := newTableWriter(ctx, [][]string{
+		{"NAME", "Name"},
+		{"VM", "VMName"},
+		{"PHASE", "Phase"},
+		{"SOURCE NODE", "SourceNode"},
+		{"TARGET NODE", "TargetNode"},
+		{"CREATION TIMESTAMP", "CreationTimestamp"},
+	})
+
+	for _, vmim := range vmimList.Items {
+		if ctx.NArg() > 0 && !wildcard.Match(ctx.Args().First(), vmim.Spec.VMIName) {
+			continue
+		}
+
+		writer.Write(buildVMMigrationData(&vmim, migrationStates[string(vmim.UID)]))
+	}
+
+	return writer.Close()
+}
+
+// buildVMMigrationData creates an object to display for a migration, the nodes are only known for the last migration of a running VM
+func buildVMMigrationData(vmim *VMv1.VirtualMachineInstanceMigration, state *VMv1.VirtualMachineInstanceMigrationState) *VMMigrationData {
+	data := &VMMigrationData{
+		Name:                            vmim.Name,
+		VMName:                          vmim.Spec.VMIName,
+		Phase:                           string(vmim.Status.Phase),
+		CreationTimestamp:               vmim.CreationTimestamp.Format(time.RFC822),
+		VirtualMachineInstanceMigration: *vmim,
+	}
+
+	if state != nil {
+		data.SourceNode = state.SourceNode
+		data.TargetNode = state.TargetNode
+	}
+
+	return data
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestBuildVMMigration(t *testing.T) {
+	vmim := buildVMMigration("default", "vm1")
+	if vmim.Namespace != "default" || vmim.GenerateName != "vm1-" || vmim.Spec.VMIName != "vm1" {
+		t.Errorf("Expected a migration of default/vm1, got %+v", vmim)
+	}
+}
+
+func TestPostVMActionWithClient(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/harvester/kubevirt.io.virtualmachines/default/vm1" || r.URL.Query().Get("action") != "migrate" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer token-abcde" {
+			t.Errorf("Expected the token in the Authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("Error decoding request body: %v", err)
+		}
+
+		if body["nodeName"] == "node3" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, "node3 is cordoned")
+		}
+	}))
+	defer server.Close()
+
+	err := postVMActionWithClient(server.Client(), server.URL, "token-abcde", "default", "vm1", "migrate", vmMigrateActionInput("node2"))
+	if err != nil {
+		t.Fatalf("Error posting action: %v", err)
+	}
+	if !reflect.DeepEqual(body, map[string]string{"nodeName": "node2"}) {
+		t.Errorf("Expected the target node in the action body, got %v", body)
+	}
+
+	err = postVMActionWithClient(server.Client(), server.URL, "token-abcde", "default", "vm1", "migrate", vmMigrateActionInput("node3"))
+	if err == nil || err.Error() != "migrate action on VM vm1 was not successful: 422 Unprocessable Entity node3 is cordoned" {
+		t.Errorf("Expected the error returned by Harvester, got %v", err)
+	}
+}
+
+func TestActiveVMMigration(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	newVMIM := func(name string, vmName string, phase VMv1.VirtualMachineInstanceMigrationPhase, age time.Duration) VMv1.VirtualMachineInstanceMigration {
+		return VMv1.VirtualMachineInstanceMigration{
+			ObjectMeta: k8smetav1.ObjectMeta{Name: name, CreationTimestamp: k8smetav1.NewTime(now.Add(-age))},
+			Spec:       VMv1.VirtualMachineInstanceMigrationSpec{VMIName: vmName},
+			Status:     VMv1.VirtualMachineInstanceMigrationStatus{Phase: phase},
+		}
+	}
+
+	vmims := []VMv1.VirtualMachineInstanceMigration{
+		newVMIM("vm1-old", "vm1", VMv1.MigrationRunning, time.Hour),
+		newVMIM("vm1-new", "vm1", VMv1.MigrationScheduling, time.Minute),
+		newVMIM("vm1-done", "vm1", VMv1.MigrationSucceeded, time.Second),
+		newVMIM("vm2-failed", "vm2", VMv1.MigrationFailed, time.Second),
+	}
+
+	active := activeVMMigration(vmims, "vm1")
+	if active == nil || active.Name != "vm1-new" {
+		t.Errorf("Expected the most recent unfinished migration vm1-new, got %v", active)
+	}
+
+	if active := activeVMMigration(vmims, "vm2"); active != nil {
+		t.Errorf("Expected no active migration for vm2, got %s", active.Name)
+	}
+}
+
+func TestWaitForVMMigrations(t *testing.T) {
+	var timeouts []time.Duration
+	failed := waitForVMMigrations([]string{"vm1", "vm2", "vm3"}, map[string]string{"vm1": "vm1-abcde", "vm3": "vm3-abcde"}, time.Minute,
+		func(migrationName string, timeout time.Duration) error {
+			timeouts = append(timeouts, timeout)
+			time.Sleep(20 * time.Millisecond)
+			if migrationName == "vm3-abcde" {
+				return fmt.Errorf("migration %s failed", migrationName)
+			}
+			return nil
+		})
+
+	if !reflect.DeepEqual(failed, []string{"vm3"}) {
+		t.Errorf("Expected the migration of vm3 to fail, got %v", failed)
+	}
+	if len(timeouts) != 2 {
+		t.Fatalf("Expected a wait for the 2 started migrations, got %d", len(timeouts))
+	}
+	if timeouts[0] > time.Minute || timeouts[1] > timeouts[0]-20*time.Millisecond {
+		t.Errorf("Expected the waits to share the same deadline, got %v", timeouts)
+	}
+}
+
+func TestExpandVMNames(t *testing.T) {
+	c := newTestHarvesterClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/kubevirt.io/v1/namespaces/staging/virtualmachines" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		writeTestJSON(t, w, &VMv1.VirtualMachineList{
+			TypeMeta: k8smetav1.TypeMeta{Kind: "VirtualMachineList", APIVersion: "kubevirt.io/v1"},
+			Items: []VMv1.VirtualMachine{
+				{ObjectMeta: k8smetav1.ObjectMeta{Name: "web-1", Namespace: "staging"}},
+				{ObjectMeta: k8smetav1.ObjectMeta{Name: "web-2", Namespace: "staging"}},
+				{ObjectMeta: k8smetav1.ObjectMeta{Name: "db-1", Namespace: "staging"}},
+			},
+		})
+	}))
+
+	vmNames, err := expandVMNames(c, "staging", []string{"db-2", "web-*"})
+	if err != nil {
+		t.Fatalf("Error expanding VM names: %v", err)
+	}
+	if !reflect.DeepEqual(vmNames, []string{"db-2", "web-1", "web-2"}) {
+		t.Errorf("Expected the names to be kept and the wildcards expanded, got %v", vmNames)
+	}
+
+	_, err = expandVMNames(c, "staging", []string{"app-*"})
+	if err == nil {
+		t.Errorf("Expected an error when no VM matches")
+	}
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable      = "table"
+	outputWide       = "wide"
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputJSONPath   = "jsonpath"
+	outputGoTemplate = "go-template"
+)
+
+var outputFlag = cli.StringFlag{
+	Name:    "output",
+	Aliases: []string{"o"},
+	Usage:   "Output format, one of table, wide, json, yaml, jsonpath=TEMPLATE or go-template=TEMPLATE",
+	EnvVars: []string{"HARVESTER_OUTPUT"},
+	Value:   outputTable,
+}
+
+var noHeadersFlag = cli.BoolFlag{
+	Name:  "no-headers",
+	Usage: "Do not print the headers of the tables",
+}
+
+// OutputFlags returns the flags selecting the output format, they are defined on the app and on each command printing objects
+func OutputFlags() []cli.Flag {
+	return []cli.Flag{&outputFlag, &noHeadersFlag}
+}
+
+// outputOptions holds the output format selected by the flags of OutputFlags
+type outputOptions struct {
+	format    string
+	template  string
+	noHeaders bool
+	sortBy    string
+}
+
+// apiObjectData is implemented by the display structures built from an API object, the machine readable formats print that object instead of the display structure
+type apiObjectData interface {
+	APIObject() interface{}
+}
+
+// getOutputOptions returns the output format given on the command line, either after the command or before it as a global flag
+func getOutputOptions(ctx *cli.Context) (outputOptions, error) {
+	format, tmpl, _ := strings.Cut(flagContext(ctx, outputFlag.Name).String(outputFlag.Name), "=")
+	options := outputOptions{
+		format:    format,
+		template:  tmpl,
+		noHeaders: flagContext(ctx, noHeadersFlag.Name).Bool(noHeadersFlag.Name),
+		sortBy:    ctx.String(sortByFlag.Name),
+	}
+
+	switch format {
+	case outputTable, outputWide, outputJSON, outputYAML:
+		if tmpl != "" {
+			return options, fmt.Errorf("the output format %s does not take a template", format)
+		}
+	case outputJSONPath, outputGoTemplate:
+		if tmpl == "" {
+			return options, fmt.Errorf("the output format %s requires a template, for example %s={.items[*].metadata.name}", format, format)
+		}
+	default:
+		return options, fmt.Errorf("invalid output format %s, must be one of table, wide, json, yaml, jsonpath=TEMPLATE or go-template=TEMPLATE", format)
+	}
+
+	return options, nil
+}
+
+// flagContext returns the nearest context of the lineage in which a flag is set, or ctx if it is set in none of them
+// this lets a flag defined both on the app and on a command be given either before or after the command name
+func flagContext(ctx *cli.Context, name string) *cli.Context {
+	for _, c := range ctx.Lineage() {
+		if c.IsSet(name) {
+			return c
+		}
+	}
+	return ctx
+}
+
+// machineReadable returns true if the objects are printed in a format meant for programs rather than as a table
+func (o outputOptions) machineReadable() bool {
+	return o.format != outputTable && o.format != outputWide
+}
+
+// TableWriter prints objects as the rows of a table, or gathers them to print them as a list in a machine readable format on Close
+type TableWriter struct {
+	options       outputOptions
+	out           io.Writer
+	writer        *tabwriter.Writer
+	header        string
+	row           *template.Template
+	sortKey       *template.Template
+	sortedRows    []sortedRow
+	headerPrinted bool
+	items         []interface{}
+	err           error
+}
+
+// sortedRow is an object kept by a TableWriter until it is closed, with the value of the column by which it is sorted
+type sortedRow struct {
+	obj interface{}
+	key string
+}
+
+// newTableWriter returns a TableWriter printing to the standard output in the format given on the command line
+// columns are pairs of a header and a field of the written objects, or a template, the wideColumns are only printed with the wide format
+// when --sort-by is given, the objects are kept until Close and sorted by the value of the column
+func newTableWriter(ctx *cli.Context, columns [][]string, wideColumns ...[]string) *TableWriter {
+	options, err := getOutputOptions(ctx)
+	t := newTableWriterWithOptions(os.Stdout, options, columns, wideColumns...)
+	if err != nil {
+		t.err = err
+	}
+	return t
+}
+
+// newTableWriterWithOptions returns a TableWriter printing to out in the format of options
+func newTableWriterWithOptions(out io.Writer, options outputOptions, columns [][]string, wideColumns ...[]string) *TableWriter {
+	allColumns := append(append([][]string{}, columns...), wideColumns...)
+	if options.format == outputWide {
+		columns = allColumns
+	}
+
+	t := &TableWriter{
+		options: options,
+		out:     out,
+		writer:  tabwriter.NewWriter(out, 10, 1, 3, ' ', 0),
+		items:   []interface{}{},
+	}
+	var rowFormat string
+	t.header, rowFormat = SimpleFormat(columns)
+	t.row, t.err = template.New("row").Parse(rowFormat)
+	if t.err != nil || options.sortBy == "" {
+		return t
+	}
+
+	sortColumn := findColumn(allColumns, options.sortBy)
+	if sortColumn == nil {
+		headers := make([]string, 0, len(allColumns))
+		for _, column := range allColumns {
+			headers = append(headers, strings.ToLower(column[0]))
+		}
+		t.err = fmt.Errorf("invalid column %s for --sort-by, must be one of %s", options.sortBy, strings.Join(headers, ", "))
+		return t
+	}
+	_, sortFormat := SimpleFormat([][]string{sortColumn})
+	t.sortKey, t.err = template.New("sortKey").Parse(strings.TrimSuffix(sortFormat, "\n"))
+
+	return t
+}
+
+// findColumn returns the column which header or field is name, ignoring the case, spaces, dashes and underscores, or nil if there is none
+func findColumn(columns [][]string, name string) []string {
+	normalize := strings.NewReplacer(" ", "", "-", "", "_", "")
+	name = normalize.Replace(strings.ToLower(name))
+	for _, column := range columns {
+		if normalize.Replace(strings.ToLower(column[0])) == name || normalize.Replace(strings.ToLower(column[1])) == name {
+			return column
+		}
+	}
+	return nil
+}
+
+// lessSortKey compares the values of a column, as quantities like 4 or 2Gi, as timestamps, or else as strings
+func lessSortKey(a string, b string) bool {
+	quantityA, errA := resource.ParseQuantity(a)
+	quantityB, errB := resource.ParseQuantity(b)
+	if errA == nil && errB == nil {
+		return quantityA.Cmp(quantityB) < 0
+	}
+
+	timeA, errA := time.Parse(time.RFC822, a)
+	timeB, errB := time.Parse(time.RFC822, b)
+	if errA == nil && errB == nil {
+		return timeA.Before(timeB)
+	}
+
+	return a < b
+}
+
+// Write prints an object as a row of the table, or keeps it to print it on Close
+func (t *TableWriter) Write(obj interface{}) {
+	if t.err != nil {
+		return
+	}
+
+	if t.sortKey != nil {
+		key := &strings.Builder{}
+		t.err = t.sortKey.Execute(key, obj)
+		t.sortedRows = append(t.sortedRows, sortedRow{obj: obj, key: key.String()})
+		return
+	}
+
+	t.write(obj)
+}
+
+// write prints an object as a row of the table, or keeps it for the list printed in a machine readable format
+func (t *TableWriter) write(obj interface{}) {
+	if t.options.machineReadable() {
+		if data, ok := obj.(apiObjectData); ok {
+			obj = data.APIObject()
+		}
+		t.items = append(t.items, obj)
+		return
+	}
+
+	t.writeHeader()
+	if t.err == nil {
+		t.err = t.row.Execute(t.writer, obj)
+	}
+}
+
+// Close prints the table, or the list of the written objects in a machine readable format
+func (t *TableWriter) Close() error {
+	if t.err != nil {
+		return t.err
+	}
+
+	if t.sortKey != nil {
+		sort.SliceStable(t.sortedRows, func(i, j int) bool {
+			return lessSortKey(t.sortedRows[i].key, t.sortedRows[j].key)
+		})
+		for _, row := range t.sortedRows {
+			if t.err == nil {
+				t.write(row.obj)
+			}
+		}
+		if t.err != nil {
+			return t.err
+		}
+	}
+
+	if t.options.machineReadable() {
+		t.err = writeObject(t.out, t.options, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      t.items,
+		})
+		return t.err
+	}
+
+	t.writeHeader()
+	if t.err == nil {
+		t.err = t.writer.Flush()
+	}
+	return t.err
+}
+
+// Err returns the first error which occurred while writing
+func (t *TableWriter) Err() error {
+	return t.err
+}
+
+func (t *TableWriter) writeHeader() {
+	if t.headerPrinted || t.options.noHeaders {
+		return
+	}
+	t.headerPrinted = true
+	_, t.err = io.WriteString(t.writer, t.header)
+}
+
+// printObject prints a single object if a machine readable format is given on the command line, it returns false if the command must print the object in its own layout
+func printObject(ctx *cli.Context, obj interface{}) (bool, error) {
+	options, err := getOutputOptions(ctx)
+	if err != nil {
+		return true, err
+	}
+	if !options.machineReadable() {
+		return false, nil
+	}
+
+	return true, writeObject(os.Stdout, options, obj)
+}
+
+// writeObject prints an object in a machine readable format, the templates are executed on its JSON representation like kubectl does
+func writeObject(out io.Writer, options outputOptions, obj interface{}) error {
+	switch options.format {
+	case outputJSON:
+		objJSON, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed during encoding the output to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(objJSON))
+		return err
+	case outputYAML:
+		objYAML, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed during encoding the output to YAML: %w", err)
+		}
+		_, err = out.Write(objYAML)
+		return err
+	}
+
+	objJSON, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed during encoding the output to JSON: %w", err)
+	}
+	var generic interface{}
+	err = json.Unmarshal(objJSON, &generic)
+	if err != nil {
+		return fmt.Errorf("failed during decoding the output from JSON: %w", err)
+	}
+
+	if options.format == outputJSONPath {
+		path := jsonpath.New("output").AllowMissingKeys(true)
+		err = path.Parse(options.template)
+		if err != nil {
+			return fmt.Errorf("error parsing the jsonpath template %s: %w", options.template, err)
+		}
+		return path.Execute(out, generic)
+	}
+
+	tmpl, err := template.New("output").Parse(options.template)
+	if err != nil {
+		return fmt.Errorf("error parsing the go-template %s: %w", options.template, err)
+	}
+	return tmpl.Execute(out, generic)
+}
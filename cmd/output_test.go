@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestTableWriterFormats(t *testing.T) {
+	columns := [][]string{
+		{"NAME", "Name"},
+		{"STATE", "State"},
+	}
+	wideColumns := [][]string{
+		{"NAMESPACE", "VirtualMachine.Namespace"},
+	}
+	vm := VirtualMachineData{
+		Name:  "vm1",
+		State: "Running",
+		VirtualMachine: VMv1.VirtualMachine{
+			ObjectMeta: k8smetav1.ObjectMeta{Name: "vm1", Namespace: "default"},
+		},
+	}
+
+	tests := []struct {
+		options  outputOptions
+		expected string
+	}{
+		{outputOptions{format: outputTable}, "NAME      STATE\nvm1       Running\n"},
+		{outputOptions{format: outputTable, noHeaders: true}, "vm1       Running\n"},
+		{outputOptions{format: outputWide}, "NAME      STATE     NAMESPACE\nvm1       Running   default\n"},
+		{outputOptions{format: outputJSONPath, template: "{.items[*].metadata.name}"}, "vm1"},
+		{outputOptions{format: outputGoTemplate, template: "{{range .items}}{{.metadata.namespace}}/{{.metadata.name}}{{end}}"}, "default/vm1"},
+		{outputOptions{format: outputYAML}, "apiVersion: v1\nitems:\n- metadata:\n    creationTimestamp: null\n    name: vm1\n    namespace: default\n  spec:\n    template: null\n  status: {}\nkind: List\n"},
+	}
+
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		writer := newTableWriterWithOptions(out, test.options, columns, wideColumns...)
+		writer.Write(&vm)
+		err := writer.Close()
+		if err != nil {
+			t.Errorf("Unexpected error with the %s format: %v", test.options.format, err)
+		}
+		if out.String() != test.expected {
+			t.Errorf("Expected %q with the %s format, got %q", test.expected, test.options.format, out.String())
+		}
+	}
+}
+
+func TestTableWriterDisplayStructure(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := newTableWriterWithOptions(out, outputOptions{format: outputJSON}, [][]string{{"VM", "VM"}})
+	writer.Write(&VMExecResult{Namespace: "staging", VM: "vm1", ExitCode: "0"})
+	err := writer.Close()
+
+	expected := "{\n  \"apiVersion\": \"v1\",\n  \"items\": [\n    {\n      \"Namespace\": \"staging\",\n      \"VM\": \"vm1\",\n      \"ExitCode\": \"0\",\n      \"Error\": \"\"\n    }\n  ],\n  \"kind\": \"List\"\n}\n"
+	if err != nil || out.String() != expected {
+		t.Errorf("Expected the display structure to be printed when there is no API object, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestGetOutputOptions(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected outputOptions
+		valid    bool
+	}{
+		{[]string{"harvester", "list"}, outputOptions{format: outputTable}, true},
+		{[]string{"harvester", "-o", "json", "list"}, outputOptions{format: outputJSON}, true},
+		{[]string{"harvester", "--no-headers", "list", "-o", "wide"}, outputOptions{format: outputWide, noHeaders: true}, true},
+		{[]string{"harvester", "-o", "json", "list", "-o", "yaml"}, outputOptions{format: outputYAML}, true},
+		{[]string{"harvester", "list", "-o", "jsonpath={.items[0].metadata.name}"}, outputOptions{format: outputJSONPath, template: "{.items[0].metadata.name}"}, true},
+		{[]string{"harvester", "list", "-o", "go-template"}, outputOptions{}, false},
+		{[]string{"harvester", "list", "-o", "json=x"}, outputOptions{}, false},
+		{[]string{"harvester", "list", "-o", "xml"}, outputOptions{}, false},
+	}
+
+	for _, test := range tests {
+		var options outputOptions
+		var optionsErr error
+		app := &cli.App{
+			Flags: OutputFlags(),
+			Commands: []*cli.Command{
+				{
+					Name:  "list",
+					Flags: OutputFlags(),
+					Action: func(ctx *cli.Context) error {
+						options, optionsErr = getOutputOptions(ctx)
+						return nil
+					},
+				},
+			},
+		}
+
+		err := app.Run(test.args)
+		if err != nil {
+			t.Fatalf("Unexpected error when running %v: %v", test.args, err)
+		}
+		if !test.valid {
+			if optionsErr == nil {
+				t.Errorf("Expected an error for %v", test.args)
+			}
+			continue
+		}
+		if optionsErr != nil || options != test.expected {
+			t.Errorf("Expected %+v for %v, got %+v (%v)", test.expected, test.args, options, optionsErr)
+		}
+	}
+}
+
+func TestTableWriterSortBy(t *testing.T) {
+	columns := [][]string{
+		{"NAME", "Name"},
+		{"RAM", "Memory"},
+	}
+	vms := []VirtualMachineData{
+		{Name: "vm1", Memory: "8Gi"},
+		{Name: "vm2", Memory: "512Mi"},
+		{Name: "vm3", Memory: "2Gi"},
+	}
+
+	out := &bytes.Buffer{}
+	writer := newTableWriterWithOptions(out, outputOptions{format: outputTable, noHeaders: true, sortBy: "ram"}, columns)
+	for i := range vms {
+		writer.Write(&vms[i])
+	}
+	err := writer.Close()
+
+	expected := "vm2       512Mi\nvm3       2Gi\nvm1       8Gi\n"
+	if err != nil || out.String() != expected {
+		t.Errorf("Expected %q, got %q (%v)", expected, out.String(), err)
+	}
+
+	writer = newTableWriterWithOptions(&bytes.Buffer{}, outputOptions{format: outputTable, sortBy: "cpu"}, columns)
+	if writer.Close() == nil {
+		t.Errorf("Expected an error when sorting by a column which does not exist")
+	}
+}
+
+func TestLessSortKey(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{"2", "10", true},
+		{"1Gi", "512Mi", false},
+		{"02 Jan 23 15:04 UTC", "01 Feb 23 10:00 UTC", true},
+		{"vm10", "vm2", true},
+		{"", "vm1", true},
+	}
+
+	for _, test := range tests {
+		if lessSortKey(test.a, test.b) != test.expected {
+			t.Errorf("Expected %s < %s to be %t", test.a, test.b, test.expected)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// forwardedPort is a local port forwarded to a port of a VM, a local port of 0 means a free port is picked
+type forwardedPort struct {
+	Local  int
+	Remote int
+}
+
+// vmPortForwardCommand defines the CLI sub-command `vm port-forward` that forwards local ports to a VM
+func vmPortForwardCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "port-forward",
+		Aliases: []string{"pf"},
+		Usage:   "Forward local ports to a VM",
+		Description: "\nForwards local ports to ports of the VM given as first argument, until interrupted. Ports are given as LOCAL:REMOTE, REMOTE when both are the same, or :REMOTE to pick a free local port. " +
+			"With --stdio, a single REMOTE port is connected to the standard input and output instead, e.g. to be used as an SSH ProxyCommand",
+		ArgsUsage: "VM_NAME [LOCAL:]REMOTE...",
+		Action:    vmPortForward,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:    "address",
+				Usage:   "Local address to listen on",
+				EnvVars: []string{"HARVESTER_PORT_FORWARD_ADDRESS"},
+				Value:   "localhost",
+			},
+			&cli.BoolFlag{
+				Name:  "stdio",
+				Usage: "Connect the standard input and output to the remote port instead of listening on a local port",
+			},
+		},
+	}
+}
+
+// vmPortForward implements the `vm port-forward` command, each local connection opens a stream on the KubeVirt VMI portforward subresource
+func vmPortForward(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("a VM name and at least one port are required")
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	if ctx.Bool("stdio") {
+		if ctx.NArg() != 2 {
+			return fmt.Errorf("only one port is accepted with --stdio")
+		}
+		port, err := parseForwardedPort(ctx.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		return portForwardStdio(ctx, namespace, vmName, port.Remote)
+	}
+
+	var ports []forwardedPort
+	for _, portSpec := range ctx.Args().Tail() {
+		port, err := parseForwardedPort(portSpec)
+		if err != nil {
+			return err
+		}
+		ports = append(ports, port)
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = getRunningVMI(c, namespace, vmName)
+	if err != nil {
+		return err
+	}
+
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", net.JoinHostPort(ctx.String("address"), strconv.Itoa(port.Local)))
+		if err != nil {
+			return fmt.Errorf("unable to listen on local port %d: %w", port.Local, err)
+		}
+		defer listener.Close()
+
+		logrus.Infof("Forwarding from %s -> %d", listener.Addr(), port.Remote)
+
+		subresource := fmt.Sprintf("portforward/%d/tcp", port.Remote)
+		go serveVMISubresource(listener, subresource, func() (*websocket.Conn, error) {
+			return dialVMISubresource(ctx, namespace, vmName, subresource)
+		})
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	<-sigs
+
+	return nil
+}
+
+// portForwardStdio forwards the standard input and output to a port of a VM until either side is closed
+func portForwardStdio(ctx *cli.Context, namespace string, vmName string, port int) error {
+	wsConn, err := dialVMISubresource(ctx, namespace, vmName, fmt.Sprintf("portforward/%d/tcp", port))
+	if err != nil {
+		return err
+	}
+	defer wsConn.Close()
+
+	done := make(chan error, 2)
+	go func() {
+		done <- copyReaderToWebsocket(wsConn, os.Stdin)
+	}()
+	go func() {
+		done <- copyWebsocketToWriter(os.Stdout, wsConn)
+	}()
+
+	return <-done
+}
+
+// parseForwardedPort parses a port specification in the format LOCAL:REMOTE, REMOTE or :REMOTE
+func parseForwardedPort(portSpec string) (forwardedPort, error) {
+	local, remote, found := strings.Cut(portSpec, ":")
+	if !found {
+		local, remote = portSpec, portSpec
+	}
+
+	remotePort, err := strconv.Atoi(remote)
+	if err != nil || remotePort < 1 || remotePort > 65535 {
+		return forwardedPort{}, fmt.Errorf("invalid remote port in %s", portSpec)
+	}
+
+	localPort := 0
+	if local != "" {
+		localPort, err = strconv.Atoi(local)
+		if err != nil || localPort < 0 || localPort > 65535 {
+			return forwardedPort{}, fmt.Errorf("invalid local port in %s", portSpec)
+		}
+	}
+
+	return forwardedPort{Local: localPort, Remote: remotePort}, nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestParseForwardedPort(t *testing.T) {
+	cases := map[string]forwardedPort{
+		"8080:80": {Local: 8080, Remote: 80},
+		"3389":    {Local: 3389, Remote: 3389},
+		":5432":   {Local: 0, Remote: 5432},
+	}
+
+	for portSpec, expected := range cases {
+		port, err := parseForwardedPort(portSpec)
+		if err != nil || port != expected {
+			t.Errorf("Expected %v for %s, got %v (%v)", expected, portSpec, port, err)
+		}
+	}
+
+	for _, portSpec := range []string{"8080:", "abc", "70000", "-1:22"} {
+		_, err := parseForwardedPort(portSpec)
+		if err == nil {
+			t.Errorf("Expected an error for %s", portSpec)
+		}
+	}
+}
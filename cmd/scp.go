@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// scpLocation is a source or destination of the scp command, VM is empty for a local path
+type scpLocation struct {
+	VM   string
+	Path string
+}
+
+// SCPCommand defines the CLI command that copies files between the local machine and a VM over SFTP
+func SCPCommand() *cli.Command {
+	flags := []cli.Flag{
+		&nsFlag,
+		&cli.BoolFlag{
+			Name:    "recursive",
+			Aliases: []string{"r"},
+			Usage:   "Copy directories recursively",
+		},
+		&cli.IntFlag{
+			Name:    "ssh-port",
+			Usage:   "TCP port to be used to connect to the VM using SSH",
+			EnvVars: []string{"HARVESTER_VM_SSH_PORT"},
+			Value:   22,
+		},
+		&cli.BoolFlag{
+			Name:    "pod-network",
+			Usage:   "Connect to the VM through the pod network",
+			EnvVars: []string{"HARVESTER_VM_POD_NETWORK"},
+		},
+	}
+
+	return &cli.Command{
+		Name:  "scp",
+		Usage: "Copy files to or from a VM",
+		Description: "\nCopies files between the local machine and a VM using SFTP, remote paths are given as VM_NAME:PATH. " +
+			"Either all sources are local and the destination is remote, or all sources are on the same VM and the destination is local",
+		ArgsUsage: "SOURCE... DESTINATION",
+		Action:    vmSCP,
+		Flags:     append(flags, sshFlags()...),
+	}
+}
+
+// vmSCP implements the `scp` command
+func vmSCP(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("at least one source and a destination are required")
+	}
+
+	args := ctx.Args().Slice()
+	destination := parseSCPLocation(args[len(args)-1])
+	var sources []scpLocation
+	for _, arg := range args[:len(args)-1] {
+		sources = append(sources, parseSCPLocation(arg))
+	}
+
+	vmName := destination.VM
+	for _, source := range sources {
+		switch {
+		case destination.VM != "" && source.VM != "":
+			return fmt.Errorf("copying between two VMs is not supported, %s and %s are both remote", source.VM+":"+source.Path, destination.VM+":"+destination.Path)
+		case destination.VM == "" && source.VM == "":
+			return fmt.Errorf("either the sources or the destination must be on a VM, in the format VM_NAME:PATH")
+		case destination.VM == "" && vmName != "" && source.VM != vmName:
+			return fmt.Errorf("all sources must be on the same VM")
+		case destination.VM == "":
+			vmName = source.VM
+		}
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	k, err := GetKubeClient(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	restConf, err := GetRESTClientAndConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("error when setting up Kubernetes API client: %w", err)
+	}
+
+	clientConfig, closeAgent, err := sshClientConfig(sshOptionsFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeAgent()
+
+	sshClient, err := dialVMSSH(c, k, restConf, ctx.String("namespace"), vmName, ctx.Int("ssh-port"), ctx.Bool("pod-network"), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return fmt.Errorf("error during start of SFTP session on VM %s: %w", vmName, err)
+	}
+	defer sftpClient.Close()
+
+	multipleSources := len(sources) > 1
+	for _, source := range sources {
+		if destination.VM != "" {
+			err = uploadPath(sftpClient, source.Path, destination.Path, ctx.Bool("recursive"), multipleSources)
+		} else {
+			err = downloadPath(sftpClient, source.Path, destination.Path, ctx.Bool("recursive"), multipleSources)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseSCPLocation splits an argument in the format VM_NAME:PATH, an argument without a VM name, such as a Windows path with a drive letter, is a local path
+func parseSCPLocation(arg string) scpLocation {
+	vmName, remotePath, found := strings.Cut(arg, ":")
+	if !found || len(vmName) <= 1 || strings.ContainsAny(vmName, `/\`) {
+		return scpLocation{Path: arg}
+	}
+
+	if remotePath == "" {
+		remotePath = "."
+	}
+	return scpLocation{VM: vmName, Path: remotePath}
+}
+
+// uploadPath copies a local file, or directory when recursive is set, to a VM
+// the destination must be an existing directory when there are multiple sources
+func uploadPath(client *sftp.Client, localPath string, remotePath string, recursive bool, multipleSources bool) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !recursive {
+		return fmt.Errorf("%s is a directory, use --recursive to copy it", localPath)
+	}
+
+	target := remotePath
+	remoteInfo, err := client.Stat(remotePath)
+	if err == nil && remoteInfo.IsDir() {
+		target = path.Join(remotePath, filepath.Base(localPath))
+	} else if multipleSources {
+		return fmt.Errorf("%s is not a directory on the VM", remotePath)
+	}
+
+	if !info.IsDir() {
+		return uploadFile(client, localPath, target, info)
+	}
+
+	return filepath.Walk(localPath, func(walkPath string, walkInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(localPath, walkPath)
+		if err != nil {
+			return err
+		}
+		walkTarget := path.Join(target, filepath.ToSlash(rel))
+
+		if walkInfo.IsDir() {
+			err = client.MkdirAll(walkTarget)
+			if err != nil {
+				return fmt.Errorf("error during creation of directory %s on the VM: %w", walkTarget, err)
+			}
+			return nil
+		}
+
+		if !walkInfo.Mode().IsRegular() {
+			logrus.Warnf("Skipping %s, which is not a regular file", walkPath)
+			return nil
+		}
+		return uploadFile(client, walkPath, walkTarget, walkInfo)
+	})
+}
+
+// uploadFile copies a local file to a VM, keeping its permissions
+func uploadFile(client *sftp.Client, localPath string, remotePath string, info os.FileInfo) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("error during creation of %s on the VM: %w", remotePath, err)
+	}
+	defer dst.Close()
+
+	progress := newProgressReader(src, os.Stderr, filepath.Base(localPath), 0, info.Size())
+	_, err = io.Copy(dst, progress)
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("error during copy of %s to the VM: %w", localPath, err)
+	}
+
+	err = client.Chmod(remotePath, info.Mode().Perm())
+	if err != nil {
+		logrus.Warnf("Unable to set the permissions of %s on the VM: %v", remotePath, err)
+	}
+	return nil
+}
+
+// downloadPath copies a file, or directory when recursive is set, from a VM to the local machine
+// the destination must be an existing directory when there are multiple sources
+func downloadPath(client *sftp.Client, remotePath string, localPath string, recursive bool, multipleSources bool) error {
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return fmt.Errorf("error during access to %s on the VM: %w", remotePath, err)
+	}
+	if info.IsDir() && !recursive {
+		return fmt.Errorf("%s is a directory, use --recursive to copy it", remotePath)
+	}
+
+	target := localPath
+	localInfo, err := os.Stat(localPath)
+	if err == nil && localInfo.IsDir() {
+		target = filepath.Join(localPath, path.Base(remotePath))
+	} else if multipleSources {
+		return fmt.Errorf("%s is not a directory", localPath)
+	}
+
+	if !info.IsDir() {
+		return downloadFile(client, remotePath, target, info)
+	}
+
+	walker := client.Walk(remotePath)
+	for walker.Step() {
+		if walker.Err() != nil {
+			return fmt.Errorf("error during listing of %s on the VM: %w", walker.Path(), walker.Err())
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), remotePath), "/")
+		walkTarget := filepath.Join(target, filepath.FromSlash(rel))
+
+		if walker.Stat().IsDir() {
+			err = os.MkdirAll(walkTarget, 0755)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !walker.Stat().Mode().IsRegular() {
+			logrus.Warnf("Skipping %s, which is not a regular file", walker.Path())
+			continue
+		}
+
+		err = downloadFile(client, walker.Path(), walkTarget, walker.Stat())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downloadFile copies a file from a VM to the local machine, keeping its permissions
+func downloadFile(client *sftp.Client, remotePath string, localPath string, info os.FileInfo) error {
+	src, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("error during opening of %s on the VM: %w", remotePath, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	progress := newProgressReader(src, os.Stderr, path.Base(remotePath), 0, info.Size())
+	_, err = io.Copy(dst, progress)
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("error during copy of %s from the VM: %w", remotePath, err)
+	}
+
+	return nil
+}
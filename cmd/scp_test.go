@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+func TestParseSCPLocation(t *testing.T) {
+	cases := map[string]scpLocation{
+		"vm1:/tmp/file": {VM: "vm1", Path: "/tmp/file"},
+		"vm1:":          {VM: "vm1", Path: "."},
+		"local.txt":     {Path: "local.txt"},
+		"./dir/a:b":     {Path: "./dir/a:b"},
+		`C:\Users\file`: {Path: `C:\Users\file`},
+	}
+
+	for arg, expected := range cases {
+		location := parseSCPLocation(arg)
+		if location != expected {
+			t.Errorf("Expected %v for %s, got %v", expected, arg, location)
+		}
+	}
+}
+
+// newTestSFTPClient returns a client of an in-memory SFTP server
+func newTestSFTPClient(t *testing.T) *sftp.Client {
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter}, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatalf("Error creating SFTP client: %v", err)
+	}
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return client
+}
+
+func TestUploadAndDownloadPath(t *testing.T) {
+	client := newTestSFTPClient(t)
+
+	localDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(localDir, "src", "sub"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(localDir, "src", "sub", "file.txt"), []byte("hello"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Mkdir("/remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = uploadPath(client, filepath.Join(localDir, "src"), "/remote", false, false)
+	if err == nil {
+		t.Errorf("Expected an error when copying a directory without --recursive")
+	}
+
+	err = uploadPath(client, filepath.Join(localDir, "src"), "/remote", true, false)
+	if err != nil {
+		t.Fatalf("Error uploading directory: %v", err)
+	}
+
+	downloadDir := filepath.Join(localDir, "download")
+	err = downloadPath(client, "/remote/src", downloadDir, true, false)
+	if err != nil {
+		t.Fatalf("Error downloading directory: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(downloadDir, "sub", "file.txt"))
+	if err != nil || string(content) != "hello" {
+		t.Errorf("Expected downloaded file with content hello, got %q (%v)", content, err)
+	}
+}
@@ -5,66 +5,70 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
-	"strings"
-	"sync"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/harvester/harvester/pkg/generated/clientset/versioned"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	portforwardclgo "k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 	"k8s.io/kubectl/pkg/cmd/portforward"
+	VMv1 "kubevirt.io/api/core/v1"
 )
 
 // ShellCommand defines the CLI command that makes it possible to ssh into a VM
 func ShellCommand() *cli.Command {
-	userHome, _ := os.UserHomeDir()
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "namespace, n",
+			Usage:   "Namespace for the VM",
+			EnvVars: []string{"HARVESTER_VM_NAMESPACE"},
+			Value:   "default",
+		},
+		&cli.IntFlag{
+			Name:    "ssh-port",
+			Usage:   "TCP port to be used to connect to the VM using SSH, default is 22",
+			EnvVars: []string{"HARVESTER_VM_SSH_PORT"},
+			Value:   22,
+		},
+		&cli.BoolFlag{
+			Name:    "pod-network",
+			Usage:   "Options to connect to VM through pod network",
+			EnvVars: []string{"HARVESTER_VM_POD_NETWORK"},
+		},
+		&cli.StringFlag{
+			Name:  "command",
+			Usage: "Command to run on the VM instead of opening an interactive shell",
+		},
+		&cli.BoolFlag{
+			Name:    "use-system-ssh",
+			Usage:   "Use the ssh binary of the system instead of the built-in SSH client",
+			EnvVars: []string{"HARVESTER_USE_SYSTEM_SSH"},
+		},
+	}
+
 	return &cli.Command{
 		Name:      "shell",
 		Aliases:   []string{"sh"},
 		Usage:     "Access a VM using SSH",
 		Action:    getShell,
 		ArgsUsage: "VM_NAME",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "namespace, n",
-				Usage:   "Namespace for the VM",
-				EnvVars: []string{"HARVESTER_VM_NAMESPACE"},
-				Value:   "default",
-			},
-			&cli.StringFlag{
-				Name:    "ssh-user, user",
-				Usage:   "SSH user to be used for connecting to VM",
-				EnvVars: []string{"HARVESTER_VM_SSH_USER"},
-				Value:   "ubuntu",
-			},
-			&cli.StringFlag{
-				Name:    "ssh-key, i",
-				Usage:   "Path to SSH Private Key to be used for connecting to VM",
-				EnvVars: []string{"HARVESTER_VM_SSH_KEY"},
-				Value:   userHome + "/.ssh/id_rsa",
-			},
-			&cli.IntFlag{
-				Name:    "ssh-port",
-				Usage:   "TCP port to be used to connect to the VM using SSH, default is 22",
-				EnvVars: []string{"HARVESTER_VM_SSH_PORT"},
-				Value:   22,
-			},
-			&cli.BoolFlag{
-				Name:    "pod-network",
-				Usage:   "Options to connect to VM through pod network",
-				EnvVars: []string{"HARVESTER_VM_POD_NETWORK"},
-			},
-		},
+		Flags:     append(flags, sshFlags()...),
 	}
 }
 
@@ -103,7 +107,7 @@ func getShell(ctx *cli.Context) error {
 	var ipAddress string
 	var sshPort string
 
-	netType, networkNum, err := networkType(vmName, c, ctx)
+	netType, networkNum, err := networkType(c, ctx.String("namespace"), vmName)
 
 	if err != nil {
 		return fmt.Errorf("error determining VM's network type: %w", err)
@@ -111,19 +115,22 @@ func getShell(ctx *cli.Context) error {
 
 	if netType == "pod" || ctx.Bool("pod-network") {
 
-		sshPort, err = getFreeLocalPort()
-		if err != nil {
-			return fmt.Errorf("unable to find free local port: %w", err)
+		if ctx.Bool("use-system-ssh") {
+			err = sshOverPortForward(k, ctx, vmName, restConf)
+		} else {
+			err = sshOverStream(k, ctx, vmName, restConf)
+		}
+		// the exit status of the remote shell is returned as is, to become the exit status of the CLI
+		if _, ok := err.(cli.ExitCoder); ok {
+			return err
 		}
-
-		err = sshOverPortForward(k, ctx, vmName, sshPort, restConf)
 		if err != nil {
 			return fmt.Errorf("ssh over Port Forwarding failed: %w", err)
 		}
 
 	} else {
 		ipAddress = vmi.Status.Interfaces[networkNum].IP
-		sshPort = "22"
+		sshPort = strconv.Itoa(ctx.Int("ssh-port"))
 
 		if ipAddress == "" {
 			return fmt.Errorf("the designated VM does not have a valid IP Address")
@@ -144,12 +151,18 @@ func getShell(ctx *cli.Context) error {
 // if a bridge network interface exists, it will be returned
 // if no bridge network interface exists, but a Pod Network interface does, it will use the last one it encounters
 // if no interface could be defined, it throws an error
-func networkType(vmName string, c *versioned.Clientset, ctx *cli.Context) (string, int, error) {
+func networkType(c *versioned.Clientset, namespace string, vmName string) (string, int, error) {
 
-	vm, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Get(context.TODO(), vmName, v1.GetOptions{})
+	vm, err := c.KubevirtV1().VirtualMachines(namespace).Get(context.TODO(), vmName, v1.GetOptions{})
 	if err != nil {
 		return "", 0, fmt.Errorf("error querying VM object: %w", err)
 	}
+
+	return vmNetworkType(vm)
+}
+
+// vmNetworkType returns the network type of a VM, bridge if it has a Multus network or pod otherwise, and the number of the network to be used
+func vmNetworkType(vm *VMv1.VirtualMachine) (string, int, error) {
 	onlyPodNetwork := false
 	podNetworkNumber := 0
 
@@ -165,34 +178,219 @@ func networkType(vmName string, c *versioned.Clientset, ctx *cli.Context) (strin
 		return "pod", podNetworkNumber, nil
 	}
 
-	return "", 0, fmt.Errorf("no valid network type found for VM: %s", vmName)
+	return "", 0, fmt.Errorf("no valid network type found for VM: %s", vm.Name)
+}
+
+// dialVMSSH opens an SSH connection to a VM, directly on its bridge network IP address or through its Pod when it is only on the Pod network or podNetwork is set
+func dialVMSSH(c *versioned.Clientset, k *kubernetes.Clientset, restConf *rest.Config, namespace string, vmName string, sshPort int, podNetwork bool, clientConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	netType, networkNum, err := networkType(c, namespace, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("error determining VM's network type: %w", err)
+	}
+
+	if netType == "pod" || podNetwork {
+		return dialSSHOverStream(k, restConf, namespace, vmName, sshPort, clientConfig)
+	}
+
+	vmi, err := getRunningVMI(c, namespace, vmName)
+	if err != nil {
+		return nil, err
+	}
+
+	if networkNum >= len(vmi.Status.Interfaces) || vmi.Status.Interfaces[networkNum].IP == "" {
+		return nil, fmt.Errorf("the VM %s does not have a valid IP Address", vmName)
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(vmi.Status.Interfaces[networkNum].IP, strconv.Itoa(sshPort)), clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error during SSH connection to %s: %w", vmName, err)
+	}
+	return client, nil
 }
 
 // getFreeLocalPort finds a random free port on the local machine as a source to the port forwarding.
 func getFreeLocalPort() (string, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
 
-	//TODO: Change implementation
-	return "32222", nil
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), nil
 }
 
-// sshOverPortFoward contains the steps to make an SSH connection to a VM that is on the PodNetwork and not on the bridge network.
-// It first finds out what is the Pod that is driving the VM
-// Then, it populates the PortForwardOptions struct that is used as a container for all the parameters necessary to port forward using Kubernetes API
-// Finally, it relies on Go Routines to open the Port forwarding tunnel and do the actual SSH connection
-func sshOverPortForward(k *kubernetes.Clientset, ctx *cli.Context, vmName string, sshPort string, restConf *rest.Config) error {
-	var err error
-	vmPodList, _ := k.CoreV1().Pods(ctx.String("namespace")).List(context.TODO(), v1.ListOptions{
-		LabelSelector: "harvesterhci.io/vmNamePrefix=" + vmName,
-	})
-
-	if len(vmPodList.Items) == 0 {
-		vmPodList, err = k.CoreV1().Pods(ctx.String("namespace")).List(context.TODO(), v1.ListOptions{
-			LabelSelector: "harvesterhci.io/vmName=" + vmName,
+// findVMPod returns the running virt-launcher Pod of a VM
+func findVMPod(k *kubernetes.Clientset, namespace string, vmName string) (*corev1.Pod, error) {
+	for _, labelSelector := range []string{"harvesterhci.io/vmNamePrefix=" + vmName, "harvesterhci.io/vmName=" + vmName} {
+		vmPodList, err := k.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{
+			LabelSelector: labelSelector,
 		})
-
 		if err != nil {
-			return fmt.Errorf("unable to find pods for the VM:%s, error: %w", vmName, err)
+			return nil, fmt.Errorf("unable to find pods for the VM:%s, error: %w", vmName, err)
 		}
+
+		for i, pod := range vmPodList.Items {
+			if pod.Status.Phase == corev1.PodRunning {
+				return &vmPodList.Items[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no running pod found for the VM: %s", vmName)
+}
+
+// portForwardURL builds the URL of the portforward subresource of a Pod from the client configuration, which keeps any path prefix of the API server such as the Rancher cluster proxy
+func portForwardURL(restClient rest.Interface, namespace string, podName string) *url.URL {
+	return restClient.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+}
+
+// sshOverStream connects to a VM on the Pod network by running the SSH protocol directly over a port forwarding stream, without any local listener
+func sshOverStream(k *kubernetes.Clientset, ctx *cli.Context, vmName string, restConf *rest.Config) error {
+	opts := sshOptionsFromContext(ctx)
+	clientConfig, closeAgent, err := sshClientConfig(opts)
+	if err != nil {
+		return err
+	}
+	defer closeAgent()
+
+	client, err := dialSSHOverStream(k, restConf, ctx.String("namespace"), vmName, ctx.Int("ssh-port"), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return runSSHSession(client, opts)
+}
+
+// dialSSHOverStream opens an SSH connection to a VM through a port forwarding stream to its Pod
+func dialSSHOverStream(k *kubernetes.Clientset, restConf *rest.Config, namespace string, vmName string, sshPort int, clientConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	vmPod, err := findVMPod(k, namespace, vmName)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("pod name: %s", vmPod.Name)
+
+	conn, err := dialPodPort(restConf, portForwardURL(k.CoreV1().RESTClient(), namespace, vmPod.Name), sshPort)
+	if err != nil {
+		return nil, err
+	}
+
+	// The VM is reached through its Pod, so it is identified in known_hosts by its name and namespace
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, net.JoinHostPort(vmName+"."+namespace, strconv.Itoa(sshPort)), clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error during SSH connection to %s: %w", vmName, err)
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// podStreamConn is a net.Conn running over the data stream of a port forwarding connection to a Pod
+type podStreamConn struct {
+	httpstream.Stream
+	streamConn httpstream.Connection
+	errs       chan error
+}
+
+// dialPodPort opens a port forwarding connection to a port of a Pod and returns its data stream as a net.Conn
+func dialPodPort(restConf *rest.Config, serverURL *url.URL, port int) (net.Conn, error) {
+	roundTripper, upgrader, err := spdy.RoundTripperFor(restConf)
+	if err != nil {
+		return nil, fmt.Errorf("error during creation of port forwarding transport: %w", err)
+	}
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: roundTripper}, http.MethodPost, serverURL)
+	streamConn, _, err := dialer.Dial(portforwardclgo.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("error during port forwarding to %s: %w", serverURL.Path, err)
+	}
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, fmt.Errorf("error during creation of port forwarding error stream: %w", err)
+	}
+	// nothing is written to the error stream
+	errorStream.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errs <- fmt.Errorf("error reading from port forwarding error stream: %w", err)
+		case len(message) > 0:
+			errs <- fmt.Errorf("port forwarding to port %d failed: %s", port, message)
+		}
+		close(errs)
+	}()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, fmt.Errorf("error during creation of port forwarding data stream: %w", err)
+	}
+
+	return &podStreamConn{Stream: dataStream, streamConn: streamConn, errs: errs}, nil
+}
+
+// Read reads from the data stream, reporting the error sent by the API server if the stream was closed because of it
+func (c *podStreamConn) Read(b []byte) (int, error) {
+	n, err := c.Stream.Read(b)
+	if err == io.EOF {
+		select {
+		case streamErr, ok := <-c.errs:
+			if ok && streamErr != nil {
+				return n, streamErr
+			}
+		default:
+		}
+	}
+	return n, err
+}
+
+// Close closes the data stream and the underlying port forwarding connection
+func (c *podStreamConn) Close() error {
+	c.Stream.Close()
+	return c.streamConn.Close()
+}
+
+func (c *podStreamConn) LocalAddr() net.Addr                { return podStreamAddr{} }
+func (c *podStreamConn) RemoteAddr() net.Addr               { return podStreamAddr{} }
+func (c *podStreamConn) SetDeadline(t time.Time) error      { return nil }
+func (c *podStreamConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *podStreamConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// podStreamAddr is the address of both ends of a podStreamConn, which do not have a network address
+type podStreamAddr struct{}
+
+func (podStreamAddr) Network() string { return "portforward" }
+func (podStreamAddr) String() string  { return "portforward" }
+
+// sshOverPortFoward contains the steps to make an SSH connection to a VM that is on the PodNetwork and not on the bridge network, using the system's SSH command.
+// It first finds out what is the Pod that is driving the VM
+// Then, it populates the PortForwardOptions struct that is used as a container for all the parameters necessary to port forward using Kubernetes API
+// Finally, it relies on Go Routines to open the Port forwarding tunnel on a free local port and do the actual SSH connection
+func sshOverPortForward(k *kubernetes.Clientset, ctx *cli.Context, vmName string, restConf *rest.Config) error {
+	vmPod, err := findVMPod(k, ctx.String("namespace"), vmName)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("pod name: %s", vmPod.Name)
+
+	sshPort, err := getFreeLocalPort()
+	if err != nil {
+		return fmt.Errorf("unable to find free local port: %w", err)
 	}
 
 	ipAddress := "localhost"
@@ -200,50 +398,68 @@ func sshOverPortForward(k *kubernetes.Clientset, ctx *cli.Context, vmName string
 	o := &portforward.PortForwardOptions{
 		Namespace:    ctx.String("namespace"),
 		Config:       restConf,
-		PodName:      vmPodList.Items[0].Name,
+		PodName:      vmPod.Name,
 		Address:      []string{ipAddress},
-		Ports:        []string{sshPort + ":22"},
+		Ports:        []string{sshPort + ":" + strconv.Itoa(ctx.Int("ssh-port"))},
 		PodClient:    k.CoreV1(),
 		StopChannel:  make(chan struct{}, 1),
 		ReadyChannel: make(chan struct{}),
 	}
-	var wg sync.WaitGroup
-	wg.Add(1)
 
-	fmt.Println("pod name:" + vmPodList.Items[0].Name)
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	forwardErr := make(chan error, 1)
 	go func() {
-		<-sigs
-		fmt.Println("Bye...")
-		close(o.StopChannel)
-		wg.Done()
+		forwardErr <- doPortForward(o, portForwardURL(k.CoreV1().RESTClient(), o.Namespace, o.PodName))
 	}()
+	defer close(o.StopChannel)
 
-	go func() {
+	select {
+	case <-o.ReadyChannel:
+	case err := <-forwardErr:
+		return err
+	case <-sigs:
+		fmt.Println("Bye...")
+		return nil
+	}
 
-		err := doPortForward(o)
-		if err != nil {
-			panic(err)
-		}
-	}()
+	return doSSH(ctx, ipAddress, sshPort)
+}
+
+// doSSH implements the actual SSHing into the VM, using the built-in SSH client unless --use-system-ssh is set
+func doSSH(ctx *cli.Context, ipAddress string, sshPort string) error {
+	if ctx.Bool("use-system-ssh") {
+		return doSystemSSH(ctx, ipAddress, sshPort)
+	}
 
-	<-o.ReadyChannel
-	err = doSSH(ctx, ipAddress, sshPort)
+	opts := sshOptionsFromContext(ctx)
+	clientConfig, closeAgent, err := sshClientConfig(opts)
 	if err != nil {
 		return err
 	}
+	defer closeAgent()
 
-	wg.Done()
-	return nil
+	client, err := ssh.Dial("tcp", net.JoinHostPort(ipAddress, sshPort), clientConfig)
+	if err != nil {
+		return fmt.Errorf("error during SSH connection to %s: %w", ipAddress, err)
+	}
+	defer client.Close()
+
+	return runSSHSession(client, opts)
 }
 
-// doSSH implements the actual SSHing into the VM. For simplicity's sake, it relies on the system's SSH command, usually present on all major OSes
+// doSystemSSH relies on the system's SSH command, usually present on all major OSes
 // Linux, Windows, MacOS
-func doSSH(ctx *cli.Context, ipAddress string, sshPort string) error {
+func doSystemSSH(ctx *cli.Context, ipAddress string, sshPort string) error {
 	sshConnString := ctx.String("ssh-user") + "@" + ipAddress
 
-	cmd := exec.Command("ssh", "-i", ctx.String("ssh-key"), "-p", sshPort, sshConnString)
+	args := []string{"-i", ctx.String("ssh-key"), "-p", sshPort, sshConnString}
+	if ctx.String("command") != "" {
+		args = append(args, ctx.String("command"))
+	}
+	cmd := exec.Command("ssh", args...)
 
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -258,23 +474,19 @@ func doSSH(ctx *cli.Context, ipAddress string, sshPort string) error {
 }
 
 // doPortForward implements the actual Port forwarding before an SSH connection can be done
-// it relies on the content of the PortForwardOptions struct defined in the upstream kubectl project
-func doPortForward(o *portforward.PortForwardOptions) error {
+// it relies on the content of the PortForwardOptions struct defined in the upstream kubectl project, serverURL is the URL of the portforward subresource of the Pod
+func doPortForward(o *portforward.PortForwardOptions, serverURL *url.URL) error {
 	roundTripper, upgrader, err := spdy.RoundTripperFor(o.Config)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("error during creation of port forwarding transport: %w", err)
 	}
 
-	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/portforward", o.Namespace, o.PodName)
-	hostIP := strings.TrimLeft(o.Config.Host, "htps:/")
-	serverURL := url.URL{Scheme: "https", Path: path, Host: hostIP}
-
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: roundTripper}, http.MethodPost, &serverURL)
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: roundTripper}, http.MethodPost, serverURL)
 	var berr, bout bytes.Buffer
 	buffErr := bufio.NewWriter(&berr)
 	buffOut := bufio.NewWriter(&bout)
 
-	fw, err := portforwardclgo.New(dialer, o.Ports, o.StopChannel, o.ReadyChannel, buffOut, buffErr)
+	fw, err := portforwardclgo.NewOnAddresses(dialer, o.Address, o.Ports, o.StopChannel, o.ReadyChannel, buffOut, buffErr)
 
 	if err != nil {
 		return fmt.Errorf("error when creating portforwarder Object: %w", err)
@@ -283,7 +495,8 @@ func doPortForward(o *portforward.PortForwardOptions) error {
 	err = fw.ForwardPorts()
 
 	if err != nil {
-		logrus.Error(buffErr)
+		buffErr.Flush()
+		logrus.Error(berr.String())
 		return fmt.Errorf("port forwarding failed: %w", err)
 	}
 	return nil
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestPortForwardURL(t *testing.T) {
+	k, err := kubernetes.NewForConfig(&rest.Config{Host: "https://rancher.example.com/k8s/clusters/c-m-abcde"})
+	if err != nil {
+		t.Fatalf("Error creating Kubernetes client: %v", err)
+	}
+
+	serverURL := portForwardURL(k.CoreV1().RESTClient(), "default", "virt-launcher-vm1-abcde")
+	expected := "https://rancher.example.com/k8s/clusters/c-m-abcde/api/v1/namespaces/default/pods/virt-launcher-vm1-abcde/portforward"
+	if serverURL.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, serverURL.String())
+	}
+}
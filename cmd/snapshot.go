@@ -0,0 +1,526 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	v1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	kubevirtAPIGroup           = "kubevirt.io"
+	virtualMachineKind         = "VirtualMachine"
+	defaultSnapshotWaitTimeout = 10 * time.Minute
+	defaultRestoreWaitTimeout  = 30 * time.Minute
+)
+
+// VMBackupData is a Data Structure that holds information to display for VM snapshots and backups
+type VMBackupData struct {
+	Name                 string
+	VMName               string
+	Type                 string
+	Ready                string
+	Target               string
+	CreationTimestamp    string
+	VirtualMachineBackup v1beta1.VirtualMachineBackup
+}
+
+// APIObject returns the VM snapshot or backup the display structure was built from
+func (d VMBackupData) APIObject() interface{} {
+	return d.VirtualMachineBackup
+}
+
+// vmSnapshotCommand defines the CLI sub-command `vm snapshot` that manages VM snapshots
+func vmSnapshotCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "snapshot",
+		Aliases: []string{"snap"},
+		Usage:   "Manage VM snapshots",
+		Action:  vmSnapshotList,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&outputFlag,
+			&noHeadersFlag,
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:        "create",
+				Aliases:     []string{"c"},
+				Usage:       "Create a snapshot of a VM",
+				Description: "\nCreates a snapshot of the VM given as an argument and waits until it is ready to use",
+				ArgsUsage:   "VM_NAME",
+				Action:      vmSnapshotCreate,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "Name of the snapshot, defaults to the VM name followed by a timestamp",
+					},
+					&cli.BoolFlag{
+						Name:  "no-wait",
+						Usage: "Do not wait for the snapshot to be ready to use",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Maximum duration to wait for the snapshot to be ready to use",
+						Value: defaultSnapshotWaitTimeout,
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Aliases:     []string{"ls"},
+				Usage:       "List VM snapshots",
+				Description: "\nLists the VM snapshots, optionally only the ones of the VM given as an argument",
+				ArgsUsage:   "[VM_NAME]",
+				Action:      vmSnapshotList,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				},
+			},
+			{
+				Name: "delete",
+				Aliases: []string{
+					"del",
+					"rm",
+				},
+				Usage:     "Delete VM snapshots",
+				ArgsUsage: "[SNAPSHOT_NAME...]",
+				Action:    vmSnapshotDelete,
+				Flags: []cli.Flag{
+					&nsFlag,
+				},
+			},
+			{
+				Name:        "restore",
+				Usage:       "Restore a VM snapshot",
+				Description: "\nRestores a VM snapshot either to a new VM (--new-vm) or by replacing the VM it was taken from (--replace), which must be stopped",
+				ArgsUsage:   "SNAPSHOT_NAME",
+				Action:      vmSnapshotRestore,
+				Flags: []cli.Flag{
+					&nsFlag,
+					&cli.StringFlag{
+						Name:  "new-vm",
+						Usage: "Name of the new VM to create from the snapshot",
+					},
+					&cli.BoolFlag{
+						Name:  "replace",
+						Usage: "Replace the VM the snapshot was taken from",
+					},
+					&cli.BoolFlag{
+						Name:  "no-wait",
+						Usage: "Do not wait for the restore to complete",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "Maximum duration to wait for the restore to complete",
+						Value: defaultRestoreWaitTimeout,
+					},
+				},
+			},
+		},
+	}
+}
+
+// vmSnapshotCreate implements the `vm snapshot create` command
+func vmSnapshotCreate(ctx *cli.Context) error {
+	return vmBackupCreateWithType(ctx, v1beta1.Snapshot)
+}
+
+// vmSnapshotList implements the `vm snapshot list` command
+func vmSnapshotList(ctx *cli.Context) error {
+	return vmBackupListWithType(ctx, v1beta1.Snapshot)
+}
+
+// vmSnapshotDelete implements the `vm snapshot delete` command
+func vmSnapshotDelete(ctx *cli.Context) error {
+	return vmBackupDeleteWithType(ctx, v1beta1.Snapshot)
+}
+
+// vmSnapshotRestore implements the `vm snapshot restore` command
+func vmSnapshotRestore(ctx *cli.Context) error {
+	return vmBackupRestoreWithType(ctx, v1beta1.Snapshot)
+}
+
+// vmBackupCreateWithType creates a VirtualMachineBackup of the given type (snapshot or backup) for the VM given as an argument.
+// Harvester implements both VM snapshots and VM backups with the same resource.
+func vmBackupCreateWithType(ctx *cli.Context, backupType v1beta1.BackupType) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the VM name")
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	_, err = c.KubevirtV1().VirtualMachines(namespace).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("no VM with the provided name found: %w", err)
+	}
+
+	backupName := ctx.String("name")
+	if backupName == "" {
+		backupName = vmName + "-" + string(backupType) + "-" + time.Now().Format("20060102-150405")
+	}
+
+	apiGroup := kubevirtAPIGroup
+	vmBackup := &v1beta1.VirtualMachineBackup{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			Name:      backupName,
+			Namespace: namespace,
+		},
+		Spec: v1beta1.VirtualMachineBackupSpec{
+			Source: v1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     virtualMachineKind,
+				Name:     vmName,
+			},
+			Type: backupType,
+		},
+	}
+
+	_, err = c.HarvesterhciV1beta1().VirtualMachineBackups(namespace).Create(context.TODO(), vmBackup, k8smetav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error during creation of %s %s: %w", backupType, backupName, err)
+	}
+	logrus.Infof("VM %s %s created for VM %s", backupType, backupName, vmName)
+
+	if ctx.Bool("no-wait") {
+		return nil
+	}
+
+	return waitForVMBackupReady(c, namespace, backupName, ctx.Duration("timeout"))
+}
+
+// vmBackupListWithType lists the VirtualMachineBackups of the given type, optionally only the ones of the VM given as an argument
+func vmBackupListWithType(ctx *cli.Context, backupType v1beta1.BackupType) error {
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	backupList, err := c.HarvesterhciV1beta1().VirtualMachineBackups(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	columns := [][]string{
+		{"NAME", "Name"},
+		{"VM", "VMName"},
+		{"READY", "Ready"},
+	}
+	if backupType == v1beta1.Backup {
+		columns = append(columns, []string{"TARGET", "Target"})
+	}
+	columns = append(columns, []string{"CREATION TIMESTAMP", "CreationTimestamp"})
+
+	writer := newTableWriter(ctx, columns)
+
+	for _, vmBackup := range filterVMBackups(backupList.Items, backupType, ctx.Args().First()) {
+		writer.Write(buildVMBackupData(&vmBackup))
+	}
+
+	return writer.Close()
+}
+
+// filterVMBackups keeps the VirtualMachineBackups of the given type, and only the ones of the VM vmName if it is not empty
+func filterVMBackups(vmBackups []v1beta1.VirtualMachineBackup, backupType v1beta1.BackupType, vmName string) []v1beta1.VirtualMachineBackup {
+	var filtered []v1beta1.VirtualMachineBackup
+	for _, vmBackup := range vmBackups {
+		if vmBackupType(&vmBackup) != backupType {
+			continue
+		}
+
+		if vmName != "" && vmBackup.Spec.Source.Name != vmName {
+			continue
+		}
+
+		filtered = append(filtered, vmBackup)
+	}
+	return filtered
+}
+
+// buildVMBackupData maps a VirtualMachineBackup to its display structure
+func buildVMBackupData(vmBackup *v1beta1.VirtualMachineBackup) *VMBackupData {
+	ready := "false"
+	if vmBackup.Status != nil && vmBackup.Status.ReadyToUse != nil && *vmBackup.Status.ReadyToUse {
+		ready = "true"
+	}
+
+	target := ""
+	if vmBackup.Status != nil && vmBackup.Status.BackupTarget != nil {
+		target = vmBackup.Status.BackupTarget.Endpoint
+	}
+
+	return &VMBackupData{
+		Name:                 vmBackup.Name,
+		VMName:               vmBackup.Spec.Source.Name,
+		Type:                 string(vmBackupType(vmBackup)),
+		Ready:                ready,
+		Target:               target,
+		CreationTimestamp:    vmBackup.CreationTimestamp.Format(time.RFC822),
+		VirtualMachineBackup: *vmBackup,
+	}
+}
+
+// vmBackupType returns the type of a VirtualMachineBackup, which defaults to backup when not set
+func vmBackupType(vmBackup *v1beta1.VirtualMachineBackup) v1beta1.BackupType {
+	if vmBackup.Spec.Type == "" {
+		return v1beta1.Backup
+	}
+	return vmBackup.Spec.Type
+}
+
+// vmBackupDeleteWithType deletes the VirtualMachineBackups given as arguments, after checking they have the expected type
+func vmBackupDeleteWithType(ctx *cli.Context, backupType v1beta1.BackupType) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("at least one %s name is required", backupType)
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	namespace := ctx.String("namespace")
+	for _, backupName := range ctx.Args().Slice() {
+		vmBackup, err := c.HarvesterhciV1beta1().VirtualMachineBackups(namespace).Get(context.TODO(), backupName, k8smetav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("no %s named %s found: %w", backupType, backupName, err)
+		}
+
+		if vmBackupType(vmBackup) != backupType {
+			return fmt.Errorf("%s is a %s and not a %s", backupName, vmBackupType(vmBackup), backupType)
+		}
+
+		err = c.HarvesterhciV1beta1().VirtualMachineBackups(namespace).Delete(context.TODO(), backupName, k8smetav1.DeleteOptions{})
+		if err != nil {
+			return fmt.Errorf("%s named %s could not be deleted successfully: %w", backupType, backupName, err)
+		}
+		logrus.Infof("VM %s %s deleted successfully", backupType, backupName)
+	}
+
+	return nil
+}
+
+// vmBackupRestoreWithType restores the VirtualMachineBackup given as an argument, either to a new VM or by replacing the source VM
+func vmBackupRestoreWithType(ctx *cli.Context, backupType v1beta1.BackupType) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("wrong number of arguments, one and only one argument is accepted by this command, and that is the %s name", backupType)
+	}
+
+	if (ctx.String("new-vm") == "") == !ctx.Bool("replace") {
+		return fmt.Errorf("exactly one of the flags --new-vm or --replace is required")
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	namespace := ctx.String("namespace")
+	backupName := ctx.Args().First()
+
+	vmBackup, err := c.HarvesterhciV1beta1().VirtualMachineBackups(namespace).Get(context.TODO(), backupName, k8smetav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("no %s named %s found: %w", backupType, backupName, err)
+	}
+
+	if vmBackupType(vmBackup) != backupType {
+		return fmt.Errorf("%s is a %s and not a %s", backupName, vmBackupType(vmBackup), backupType)
+	}
+
+	vmRestore := buildVMRestore(vmBackup, ctx.String("new-vm"), ctx.Bool("delete-volumes"))
+
+	vmRestoreCreated, err := c.HarvesterhciV1beta1().VirtualMachineRestores(namespace).Create(context.TODO(), vmRestore, k8smetav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error during restore of %s %s: %w", backupType, backupName, err)
+	}
+	logrus.Infof("Restore %s of %s %s to VM %s created", vmRestoreCreated.Name, backupType, backupName, vmRestore.Spec.Target.Name)
+
+	if ctx.Bool("no-wait") {
+		return nil
+	}
+
+	return waitForVMRestoreComplete(c, namespace, vmRestoreCreated.Name, ctx.Duration("timeout"))
+}
+
+// buildVMRestore creates the VirtualMachineRestore of a VirtualMachineBackup, to the new VM newVM if it is not empty, or replacing the source VM otherwise.
+// When the source VM is replaced, its previous volumes are deleted if deleteVolumes is true.
+func buildVMRestore(vmBackup *v1beta1.VirtualMachineBackup, newVM string, deleteVolumes bool) *v1beta1.VirtualMachineRestore {
+	apiGroup := kubevirtAPIGroup
+	vmRestore := &v1beta1.VirtualMachineRestore{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			GenerateName: "restore-" + vmBackup.Name + "-",
+			Namespace:    vmBackup.Namespace,
+		},
+		Spec: v1beta1.VirtualMachineRestoreSpec{
+			Target: v1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     virtualMachineKind,
+				Name:     vmBackup.Spec.Source.Name,
+			},
+			VirtualMachineBackupName:      vmBackup.Name,
+			VirtualMachineBackupNamespace: vmBackup.Namespace,
+			DeletionPolicy:                v1beta1.VirtualMachineRestoreRetain,
+		},
+	}
+
+	if newVM != "" {
+		vmRestore.Spec.Target.Name = newVM
+		vmRestore.Spec.NewVM = true
+	} else if deleteVolumes {
+		vmRestore.Spec.DeletionPolicy = v1beta1.VirtualMachineRestoreDelete
+	}
+
+	return vmRestore
+}
+
+// waitForVMBackupReady watches a VirtualMachineBackup until it is ready to use, reporting the messages of its conditions on the way
+func waitForVMBackupReady(c *harvclient.Clientset, namespace string, name string, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	watcher, err := c.HarvesterhciV1beta1().VirtualMachineBackups(namespace).Watch(timeoutCtx, k8smetav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("error during watching %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	reporter := conditionReporter{name: name}
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s to be ready", timeout, name)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch on %s was closed before it was ready", name)
+			}
+
+			if event.Type == watch.Deleted {
+				return fmt.Errorf("%s was deleted", name)
+			}
+
+			vmBackup, ok := event.Object.(*v1beta1.VirtualMachineBackup)
+			if !ok || vmBackup.Status == nil {
+				continue
+			}
+
+			reporter.report(vmBackup.Status.Conditions)
+
+			ready, err := vmBackupReady(vmBackup)
+			if err != nil {
+				return err
+			}
+			if ready {
+				logrus.Infof("%s is ready to use", name)
+				return nil
+			}
+		}
+	}
+}
+
+// vmBackupReady returns true if a VirtualMachineBackup is ready to use, or an error if it failed
+func vmBackupReady(vmBackup *v1beta1.VirtualMachineBackup) (bool, error) {
+	if vmBackup.Status == nil {
+		return false, nil
+	}
+
+	if vmBackup.Status.Error != nil && vmBackup.Status.Error.Message != nil {
+		return false, fmt.Errorf("%s failed: %s", vmBackup.Name, *vmBackup.Status.Error.Message)
+	}
+
+	return vmBackup.Status.ReadyToUse != nil && *vmBackup.Status.ReadyToUse, nil
+}
+
+// waitForVMRestoreComplete watches a VirtualMachineRestore until it is complete, reporting the messages of its conditions on the way
+func waitForVMRestoreComplete(c *harvclient.Clientset, namespace string, name string, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	watcher, err := c.HarvesterhciV1beta1().VirtualMachineRestores(namespace).Watch(timeoutCtx, k8smetav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
+	if err != nil {
+		return fmt.Errorf("error during watching %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	reporter := conditionReporter{name: name}
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s to complete", timeout, name)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch on %s was closed before it completed", name)
+			}
+
+			if event.Type == watch.Deleted {
+				return fmt.Errorf("%s was deleted", name)
+			}
+
+			vmRestore, ok := event.Object.(*v1beta1.VirtualMachineRestore)
+			if !ok || vmRestore.Status == nil {
+				continue
+			}
+
+			reporter.report(vmRestore.Status.Conditions)
+
+			if vmRestore.Status.Complete != nil && *vmRestore.Status.Complete {
+				logrus.Infof("%s is complete", name)
+				return nil
+			}
+		}
+	}
+}
+
+// conditionReporter logs the conditions of a resource each time their status or message changes
+type conditionReporter struct {
+	name string
+	last map[string]string
+}
+
+func (r *conditionReporter) report(conditions []v1beta1.Condition) {
+	for _, message := range r.changes(conditions) {
+		logrus.Info(message)
+	}
+}
+
+// changes returns a message for each condition which status, reason or message changed since the previous call
+func (r *conditionReporter) changes(conditions []v1beta1.Condition) []string {
+	if r.last == nil {
+		r.last = map[string]string{}
+	}
+
+	var messages []string
+	for _, condition := range conditions {
+		state := string(condition.Status) + " " + condition.Reason + " " + condition.Message
+		if r.last[string(condition.Type)] == state {
+			continue
+		}
+		r.last[string(condition.Type)] = state
+
+		if condition.Message != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s=%s %s", r.name, condition.Type, condition.Status, condition.Message))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s: %s=%s", r.name, condition.Type, condition.Status))
+		}
+	}
+	return messages
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVMBackup(name string, vmName string, backupType v1beta1.BackupType) v1beta1.VirtualMachineBackup {
+	return v1beta1.VirtualMachineBackup{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: v1beta1.VirtualMachineBackupSpec{
+			Source: corev1.TypedLocalObjectReference{Kind: virtualMachineKind, Name: vmName},
+			Type:   backupType,
+		},
+	}
+}
+
+func TestFilterVMBackups(t *testing.T) {
+	vmBackups := []v1beta1.VirtualMachineBackup{
+		newTestVMBackup("vm1-snap", "vm1", v1beta1.Snapshot),
+		newTestVMBackup("vm1-backup", "vm1", v1beta1.Backup),
+		newTestVMBackup("vm1-legacy", "vm1", ""),
+		newTestVMBackup("vm2-snap", "vm2", v1beta1.Snapshot),
+	}
+
+	names := func(filtered []v1beta1.VirtualMachineBackup) []string {
+		var result []string
+		for _, vmBackup := range filtered {
+			result = append(result, vmBackup.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		backupType v1beta1.BackupType
+		vmName     string
+		expected   []string
+	}{
+		{v1beta1.Snapshot, "", []string{"vm1-snap", "vm2-snap"}},
+		{v1beta1.Snapshot, "vm2", []string{"vm2-snap"}},
+		{v1beta1.Backup, "", []string{"vm1-backup", "vm1-legacy"}},
+		{v1beta1.Backup, "vm2", nil},
+	}
+
+	for _, test := range tests {
+		filtered := names(filterVMBackups(vmBackups, test.backupType, test.vmName))
+		if !reflect.DeepEqual(filtered, test.expected) {
+			t.Errorf("Expected %v for the %s of %q, got %v", test.expected, test.backupType, test.vmName, filtered)
+		}
+	}
+}
+
+func TestBuildVMRestore(t *testing.T) {
+	vmBackup := newTestVMBackup("vm1-snap", "vm1", v1beta1.Snapshot)
+
+	vmRestore := buildVMRestore(&vmBackup, "vm1-copy", true)
+	if vmRestore.Spec.Target.Name != "vm1-copy" || !vmRestore.Spec.NewVM {
+		t.Errorf("Expected a restore to the new VM vm1-copy, got %+v", vmRestore.Spec)
+	}
+	if vmRestore.Spec.DeletionPolicy != v1beta1.VirtualMachineRestoreRetain {
+		t.Errorf("Expected the volumes to be retained for a new VM, got %s", vmRestore.Spec.DeletionPolicy)
+	}
+
+	vmRestore = buildVMRestore(&vmBackup, "", false)
+	if vmRestore.Spec.Target.Name != "vm1" || vmRestore.Spec.NewVM {
+		t.Errorf("Expected a restore replacing vm1, got %+v", vmRestore.Spec)
+	}
+	if vmRestore.Spec.DeletionPolicy != v1beta1.VirtualMachineRestoreRetain {
+		t.Errorf("Expected the volumes to be retained, got %s", vmRestore.Spec.DeletionPolicy)
+	}
+	if vmRestore.Namespace != "default" || vmRestore.GenerateName != "restore-vm1-snap-" ||
+		vmRestore.Spec.VirtualMachineBackupName != "vm1-snap" || vmRestore.Spec.VirtualMachineBackupNamespace != "default" {
+		t.Errorf("Expected a restore of default/vm1-snap, got %+v", vmRestore)
+	}
+	if *vmRestore.Spec.Target.APIGroup != kubevirtAPIGroup || vmRestore.Spec.Target.Kind != virtualMachineKind {
+		t.Errorf("Expected a VirtualMachine target, got %+v", vmRestore.Spec.Target)
+	}
+
+	vmRestore = buildVMRestore(&vmBackup, "", true)
+	if vmRestore.Spec.DeletionPolicy != v1beta1.VirtualMachineRestoreDelete {
+		t.Errorf("Expected the volumes of the replaced VM to be deleted, got %s", vmRestore.Spec.DeletionPolicy)
+	}
+}
+
+func TestVMBackupReady(t *testing.T) {
+	vmBackup := newTestVMBackup("vm1-snap", "vm1", v1beta1.Snapshot)
+
+	ready, err := vmBackupReady(&vmBackup)
+	if ready || err != nil {
+		t.Errorf("Expected a snapshot without status not to be ready, got %t (%v)", ready, err)
+	}
+
+	readyToUse := false
+	vmBackup.Status = &v1beta1.VirtualMachineBackupStatus{ReadyToUse: &readyToUse}
+	ready, err = vmBackupReady(&vmBackup)
+	if ready || err != nil {
+		t.Errorf("Expected a snapshot not ready to use not to be ready, got %t (%v)", ready, err)
+	}
+
+	readyToUse = true
+	ready, err = vmBackupReady(&vmBackup)
+	if !ready || err != nil {
+		t.Errorf("Expected a snapshot ready to use to be ready, got %t (%v)", ready, err)
+	}
+
+	message := "volume snapshot failed"
+	vmBackup.Status.Error = &v1beta1.Error{Message: &message}
+	_, err = vmBackupReady(&vmBackup)
+	if err == nil || err.Error() != "vm1-snap failed: volume snapshot failed" {
+		t.Errorf("Expected the error of the snapshot, got %v", err)
+	}
+}
+
+func TestConditionReporterChanges(t *testing.T) {
+	reporter := conditionReporter{name: "vm1-snap"}
+
+	progressing := v1beta1.Condition{Type: "InProgress", Status: corev1.ConditionTrue, Message: "creating volume snapshots"}
+	messages := reporter.changes([]v1beta1.Condition{progressing})
+	if !reflect.DeepEqual(messages, []string{"vm1-snap: InProgress=True creating volume snapshots"}) {
+		t.Errorf("Expected the new condition to be reported, got %v", messages)
+	}
+
+	messages = reporter.changes([]v1beta1.Condition{progressing})
+	if len(messages) != 0 {
+		t.Errorf("Expected an unchanged condition not to be reported, got %v", messages)
+	}
+
+	progressing.Status = corev1.ConditionFalse
+	progressing.Message = ""
+	ready := v1beta1.Condition{Type: "Ready", Status: corev1.ConditionTrue}
+	messages = reporter.changes([]v1beta1.Condition{progressing, ready})
+	if !reflect.DeepEqual(messages, []string{"vm1-snap: InProgress=False", "vm1-snap: Ready=True"}) {
+		t.Errorf("Expected the changed conditions to be reported, got %v", messages)
+	}
+}
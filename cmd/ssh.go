@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
+)
+
+const (
+	hostKeyCheckAsk       = "ask"
+	hostKeyCheckAcceptNew = "accept-new"
+	hostKeyCheckStrict    = "strict"
+	hostKeyCheckOff       = "off"
+	sshDialTimeout        = 30 * time.Second
+	windowSizeInterval    = 250 * time.Millisecond
+	defaultTerminalType   = "xterm-256color"
+)
+
+// sshOptions holds the settings of an SSH connection to a VM
+type sshOptions struct {
+	User           string
+	KeyFile        string
+	KeyFileSet     bool
+	Password       string
+	KnownHostsFile string
+	HostKeyCheck   string
+	Command        string
+	// NoPrompt disables the password and host key prompts, for connections running in parallel
+	NoPrompt bool
+}
+
+// sshFlags returns the flags configuring the native SSH client, shared by the commands connecting to VMs over SSH
+func sshFlags() []cli.Flag {
+	userHome, _ := os.UserHomeDir()
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "ssh-user",
+			Aliases: []string{"user"},
+			Usage:   "SSH user to be used for connecting to VM",
+			EnvVars: []string{"HARVESTER_VM_SSH_USER"},
+			Value:   "ubuntu",
+		},
+		&cli.StringFlag{
+			Name:    "ssh-key",
+			Aliases: []string{"i"},
+			Usage:   "Path to SSH Private Key to be used for connecting to VM",
+			EnvVars: []string{"HARVESTER_VM_SSH_KEY"},
+			Value:   filepath.Join(userHome, ".ssh", "id_rsa"),
+		},
+		&cli.StringFlag{
+			Name:    "password",
+			Usage:   "Password to be used for connecting to VM, prompted for if needed and not set",
+			EnvVars: []string{"HARVESTER_VM_SSH_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "known-hosts",
+			Usage:   "Path to the known_hosts file used to verify the host keys of VMs",
+			EnvVars: []string{"HARVESTER_VM_SSH_KNOWN_HOSTS"},
+			Value:   filepath.Join(userHome, ".ssh", "known_hosts"),
+		},
+		&cli.StringFlag{
+			Name:    "host-key-check",
+			Usage:   "Handling of unknown host keys: ask, accept-new, strict or off",
+			EnvVars: []string{"HARVESTER_VM_SSH_HOST_KEY_CHECK"},
+			Value:   hostKeyCheckAsk,
+		},
+	}
+}
+
+// sshOptionsFromContext reads the SSH settings from the CLI flags
+func sshOptionsFromContext(ctx *cli.Context) sshOptions {
+	return sshOptions{
+		User:           ctx.String("ssh-user"),
+		KeyFile:        ctx.String("ssh-key"),
+		KeyFileSet:     ctx.IsSet("ssh-key"),
+		Password:       ctx.String("password"),
+		KnownHostsFile: ctx.String("known-hosts"),
+		HostKeyCheck:   ctx.String("host-key-check"),
+		Command:        ctx.String("command"),
+	}
+}
+
+// sshClientConfig builds the configuration of the SSH client, trying in order the SSH agent, the key file and the password
+// closeAgent closes the connection to the SSH agent, it must be called once the SSH connections using the configuration are closed
+func sshClientConfig(opts sshOptions) (clientConfig *ssh.ClientConfig, closeAgent func(), err error) {
+	var authMethods []ssh.AuthMethod
+
+	closeAgent = func() {}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		agentConn, err := net.Dial("unix", socket)
+		if err != nil {
+			logrus.Debugf("Unable to connect to the SSH agent: %v", err)
+		} else {
+			closeAgent = func() {
+				_ = agentConn.Close()
+			}
+			authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeAgent()
+		}
+	}()
+
+	signer, err := loadSSHKey(opts.KeyFile, opts.KeyFileSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if signer != nil {
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
+	if opts.Password != "" {
+		authMethods = append(authMethods, ssh.Password(opts.Password))
+	} else if !opts.NoPrompt && term.IsTerminal(int(os.Stdin.Fd())) {
+		authMethods = append(authMethods, ssh.PasswordCallback(func() (string, error) {
+			return readSecret(fmt.Sprintf("%s's password: ", opts.User))
+		}))
+	}
+
+	hostKeyCheck := opts.HostKeyCheck
+	if opts.NoPrompt && hostKeyCheck == hostKeyCheckAsk {
+		hostKeyCheck = hostKeyCheckStrict
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(opts.KnownHostsFile, hostKeyCheck)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, closeAgent, nil
+}
+
+// loadSSHKey reads a private key file, prompting for its passphrase if it is encrypted
+// a missing file is only an error when its path was given explicitly
+func loadSSHKey(keyFile string, required bool) (ssh.Signer, error) {
+	keyBytes, err := os.ReadFile(keyFile)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error during reading of SSH key %s: %w", keyFile, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	var passphraseErr *ssh.PassphraseMissingError
+	if errors.As(err, &passphraseErr) {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("SSH key %s is encrypted and no terminal is available to enter its passphrase", keyFile)
+		}
+
+		passphrase, err := readSecret(fmt.Sprintf("Enter passphrase for key '%s': ", keyFile))
+		if err != nil {
+			return nil, err
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(keyBytes, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("error during decryption of SSH key %s: %w", keyFile, err)
+		}
+		return signer, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error during parsing of SSH key %s: %w", keyFile, err)
+	}
+
+	return signer, nil
+}
+
+// readSecret prompts for a secret on the terminal without echoing it
+func readSecret(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error during reading of secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// sshHostKeyCallback verifies host keys against a known_hosts file, unknown hosts are handled according to the host key check mode
+// and added to the file when accepted. A changed host key is always rejected unless the check is off
+func sshHostKeyCallback(knownHostsFile string, mode string) (ssh.HostKeyCallback, error) {
+	switch mode {
+	case hostKeyCheckOff:
+		return ssh.InsecureIgnoreHostKey(), nil
+	case hostKeyCheckAsk, hostKeyCheckAcceptNew, hostKeyCheckStrict:
+	default:
+		return nil, fmt.Errorf("invalid host key check %s, must be one of %s, %s, %s or %s", mode, hostKeyCheckAsk, hostKeyCheckAcceptNew, hostKeyCheckStrict, hostKeyCheckOff)
+	}
+
+	err := os.MkdirAll(filepath.Dir(knownHostsFile), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("error during creation of known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(knownHostsFile, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error during opening of known_hosts file: %w", err)
+	}
+	f.Close()
+
+	known, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error during parsing of known_hosts file %s: %w", knownHostsFile, err)
+	}
+
+	// the callback may be shared by connections running in parallel, which must not prompt or write to the file at the same time
+	var lock sync.Mutex
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		err := known(hostname, remote, key)
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("the host key of %s has changed, it may be a man-in-the-middle attack or the VM may have been recreated, "+
+				"remove line %d of %s if the change is expected", hostname, keyErr.Want[0].Line, keyErr.Want[0].Filename)
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+		switch mode {
+		case hostKeyCheckStrict:
+			return fmt.Errorf("unknown host key %s for %s, use --host-key-check %s to accept it", fingerprint, hostname, hostKeyCheckAcceptNew)
+		case hostKeyCheckAsk:
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return fmt.Errorf("unknown host key %s for %s and no terminal to confirm it, use --host-key-check %s to accept it", fingerprint, hostname, hostKeyCheckAcceptNew)
+			}
+
+			fmt.Fprintf(os.Stderr, "The authenticity of host '%s' can't be established.\n%s key fingerprint is %s.\nAre you sure you want to continue connecting (yes/no)? ", hostname, key.Type(), fingerprint)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(answer)) != "yes" {
+				return fmt.Errorf("host key verification failed for %s", hostname)
+			}
+		}
+
+		return appendKnownHost(knownHostsFile, hostname, key)
+	}, nil
+}
+
+// appendKnownHost adds a host key to a known_hosts file
+func appendKnownHost(knownHostsFile string, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(knownHostsFile, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error during opening of known_hosts file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	if err != nil {
+		return fmt.Errorf("error during writing of known_hosts file: %w", err)
+	}
+
+	logrus.Infof("Permanently added %s to the list of known hosts", hostname)
+	return nil
+}
+
+// runSSHSession opens a session on the SSH client, running the command of the options if set or an interactive shell otherwise
+func runSSHSession(client *ssh.Client, opts sshOptions) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("error during creation of SSH session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	if opts.Command != "" {
+		return sshExitError(session.Run(opts.Command))
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	stdoutFd := int(os.Stdout.Fd())
+	if term.IsTerminal(stdinFd) {
+		width, height, err := term.GetSize(stdoutFd)
+		if err != nil {
+			width, height = 80, 24
+		}
+
+		termType := os.Getenv("TERM")
+		if termType == "" {
+			termType = defaultTerminalType
+		}
+
+		err = session.RequestPty(termType, height, width, ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		})
+		if err != nil {
+			return fmt.Errorf("error during allocation of a PTY: %w", err)
+		}
+
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("error during switching the terminal to raw mode: %w", err)
+		}
+		defer func() {
+			_ = term.Restore(stdinFd, oldState)
+		}()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go watchWindowSize(session, stdoutFd, width, height, stop)
+	}
+
+	err = session.Shell()
+	if err != nil {
+		return fmt.Errorf("error during start of the shell: %w", err)
+	}
+
+	return sshExitError(session.Wait())
+}
+
+// sshExitError turns the non-zero exit status of a remote command or shell into the exit status of the CLI, other errors are kept as they are
+func sshExitError(err error) error {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return cli.Exit("", exitErr.ExitStatus())
+	}
+	return err
+}
+
+// watchWindowSize forwards the changes of the terminal size to the session until stop is closed
+// the size is polled, as there is no portable way to be notified of the changes
+func watchWindowSize(session *ssh.Session, fd int, width int, height int, stop chan struct{}) {
+	ticker := time.NewTicker(windowSizeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			newWidth, newHeight, err := term.GetSize(fd)
+			if err != nil || (newWidth == width && newHeight == height) {
+				continue
+			}
+			width, height = newWidth, newHeight
+			_ = session.WindowChange(height, width)
+		}
+	}
+}
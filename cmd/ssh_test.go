@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating host key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Error converting host key: %v", err)
+	}
+	return key
+}
+
+func TestSSHHostKeyCallback(t *testing.T) {
+	knownHostsFile := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 22}
+	hostKey := newTestHostKey(t)
+
+	strict, err := sshHostKeyCallback(knownHostsFile, hostKeyCheckStrict)
+	if err != nil {
+		t.Fatalf("Error creating host key callback: %v", err)
+	}
+	if strict("10.0.0.5:22", remote, hostKey) == nil {
+		t.Errorf("Expected an unknown host key to be rejected in strict mode")
+	}
+
+	acceptNew, err := sshHostKeyCallback(knownHostsFile, hostKeyCheckAcceptNew)
+	if err != nil {
+		t.Fatalf("Error creating host key callback: %v", err)
+	}
+	if err := acceptNew("10.0.0.5:22", remote, hostKey); err != nil {
+		t.Errorf("Expected an unknown host key to be accepted, got %v", err)
+	}
+
+	strict, err = sshHostKeyCallback(knownHostsFile, hostKeyCheckStrict)
+	if err != nil {
+		t.Fatalf("Error creating host key callback: %v", err)
+	}
+	if err := strict("10.0.0.5:22", remote, hostKey); err != nil {
+		t.Errorf("Expected the added host key to be known, got %v", err)
+	}
+
+	acceptNew, err = sshHostKeyCallback(knownHostsFile, hostKeyCheckAcceptNew)
+	if err != nil {
+		t.Fatalf("Error creating host key callback: %v", err)
+	}
+	if acceptNew("10.0.0.5:22", remote, newTestHostKey(t)) == nil {
+		t.Errorf("Expected a changed host key to be rejected")
+	}
+
+	_, err = sshHostKeyCallback(knownHostsFile, "maybe")
+	if err == nil {
+		t.Errorf("Expected an error for an invalid host key check")
+	}
+}
+
+// newTestSSHClient connects an SSH client to an in-process server, which exits each command with the status given as the command
+func newTestSSHClient(t *testing.T) *ssh.Client {
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivateKey)
+	if err != nil {
+		t.Fatalf("Error converting host key: %v", err)
+	}
+
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening on a local port: %v", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, channels, requests, err := ssh.NewServerConn(serverConn, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(requests)
+
+		for newChannel := range channels {
+			channel, channelRequests, err := newChannel.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer channel.Close()
+				for request := range channelRequests {
+					if request.Type != "exec" {
+						_ = request.Reply(false, nil)
+						continue
+					}
+					_ = request.Reply(true, nil)
+
+					var command struct{ Value string }
+					_ = ssh.Unmarshal(request.Payload, &command)
+					var status uint32
+					fmt.Sscan(command.Value, &status)
+					_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+					return
+				}
+			}()
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "ubuntu",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Error connecting to the SSH server: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func TestRunSSHSessionExitStatus(t *testing.T) {
+	client := newTestSSHClient(t)
+
+	err := runSSHSession(client, sshOptions{Command: "0"})
+	if err != nil {
+		t.Errorf("Expected no error for a successful command, got %v", err)
+	}
+
+	err = runSSHSession(client, sshOptions{Command: "3"})
+	exitCoder, ok := err.(cli.ExitCoder)
+	if !ok || exitCoder.ExitCode() != 3 {
+		t.Errorf("Expected the exit status 3 of the command, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	sshConfigFormatSSH     = "ssh"
+	sshConfigFormatAnsible = "ansible"
+)
+
+var ansibleGroupInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sshHostEntry is a Data Structure that holds the SSH settings of a VM
+type sshHostEntry struct {
+	Alias        string
+	HostName     string
+	Port         int
+	User         string
+	IdentityFile string
+	ProxyCommand string
+	Namespace    string
+	Labels       map[string]string
+}
+
+// ansibleGroup is a group of an Ansible YAML inventory
+type ansibleGroup struct {
+	Hosts    map[string]map[string]interface{} `yaml:"hosts,omitempty"`
+	Children map[string]*ansibleGroup          `yaml:"children,omitempty"`
+}
+
+// SSHConfigCommand defines the CLI command that generates an ssh_config or an Ansible inventory of the VMs
+func SSHConfigCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "ssh-config",
+		Usage: "Generate an ssh_config or an Ansible inventory for the VMs",
+		Description: "\nPrints a Host block for every VM, named VM_NAME.NAMESPACE, which can be included in ~/.ssh/config. " +
+			"VMs on a bridge network are reached on their IP address, VMs only on the pod network through a ProxyCommand calling `vm port-forward --stdio`",
+		Action: vmSSHConfig,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:    "selector",
+				Aliases: []string{"l"},
+				Usage:   "Label selector of the VMs to include, e.g. app=web",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format: " + sshConfigFormatSSH + " or " + sshConfigFormatAnsible,
+				Value: sshConfigFormatSSH,
+			},
+			&cli.StringFlag{
+				Name:    "ssh-user",
+				Aliases: []string{"user"},
+				Usage:   "SSH user to be used for connecting to the VMs",
+				EnvVars: []string{"HARVESTER_VM_SSH_USER"},
+				Value:   "ubuntu",
+			},
+			&cli.StringFlag{
+				Name:    "ssh-key",
+				Aliases: []string{"i"},
+				Usage:   "Path to SSH Private Key to be used for connecting to the VMs",
+				EnvVars: []string{"HARVESTER_VM_SSH_KEY"},
+			},
+			&cli.IntFlag{
+				Name:    "ssh-port",
+				Usage:   "TCP port to be used to connect to the VMs using SSH",
+				EnvVars: []string{"HARVESTER_VM_SSH_PORT"},
+				Value:   22,
+			},
+			&cli.BoolFlag{
+				Name:    "pod-network",
+				Usage:   "Connect to all VMs through the pod network",
+				EnvVars: []string{"HARVESTER_VM_POD_NETWORK"},
+			},
+		},
+	}
+}
+
+// vmSSHConfig implements the `ssh-config` command
+func vmSSHConfig(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != sshConfigFormatSSH && format != sshConfigFormatAnsible {
+		return fmt.Errorf("invalid format %s, must be %s or %s", format, sshConfigFormatSSH, sshConfigFormatAnsible)
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	vmList, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{
+		LabelSelector: ctx.String("selector"),
+	})
+	if err != nil {
+		return fmt.Errorf("error during listing of VMs: %w", err)
+	}
+
+	vmiList, err := c.KubevirtV1().VirtualMachineInstances(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error during listing of VMIs: %w", err)
+	}
+
+	vmiMap := map[string]*VMv1.VirtualMachineInstance{}
+	for i, vmi := range vmiList.Items {
+		vmiMap[vmi.Namespace+"/"+vmi.Name] = &vmiList.Items[i]
+	}
+
+	proxyCommand, err := sshProxyCommand(ctx)
+	if err != nil {
+		return err
+	}
+
+	var entries []sshHostEntry
+	for i, vm := range vmList.Items {
+		entry, err := buildSSHHostEntry(&vmList.Items[i], vmiMap[vm.Namespace+"/"+vm.Name], ctx.Bool("pod-network"), proxyCommand)
+		if err != nil {
+			logrus.Warnf("Skipping VM %s: %v", vm.Name, err)
+			continue
+		}
+		entry.Port = ctx.Int("ssh-port")
+		entry.User = ctx.String("ssh-user")
+		entry.IdentityFile = ctx.String("ssh-key")
+		entries = append(entries, entry)
+	}
+
+	if format == sshConfigFormatAnsible {
+		return writeAnsibleInventory(os.Stdout, entries)
+	}
+	return writeSSHConfig(os.Stdout, entries)
+}
+
+// sshProxyCommand returns the beginning of the ProxyCommand calling back into this CLI with the same Harvester configuration
+func sshProxyCommand(ctx *cli.Context) (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("unable to find the path of the harvester executable: %w", err)
+	}
+
+	return fmt.Sprintf("%s --harvester-config %s vm port-forward --stdio",
+		quoteSSHConfigValue(executable), quoteSSHConfigValue(os.ExpandEnv(ctx.String("harvester-config")))), nil
+}
+
+// buildSSHHostEntry creates the SSH settings of a VM, VMs only on the pod network, or all of them if podNetwork is set, are reached through the proxy command
+// a VM on a bridge network must be running with an IP address
+func buildSSHHostEntry(vm *VMv1.VirtualMachine, vmi *VMv1.VirtualMachineInstance, podNetwork bool, proxyCommand string) (sshHostEntry, error) {
+	entry := sshHostEntry{
+		Alias:     vm.Name + "." + vm.Namespace,
+		Namespace: vm.Namespace,
+		Labels:    vm.Labels,
+	}
+
+	netType, networkNum, err := vmNetworkType(vm)
+	if err != nil {
+		return entry, err
+	}
+
+	if netType == "pod" || podNetwork {
+		entry.HostName = entry.Alias
+		entry.ProxyCommand = fmt.Sprintf("%s --namespace %s %s %%p", proxyCommand, vm.Namespace, vm.Name)
+		return entry, nil
+	}
+
+	if vmi == nil || networkNum >= len(vmi.Status.Interfaces) || vmi.Status.Interfaces[networkNum].IP == "" {
+		return entry, fmt.Errorf("the VM does not have a valid IP Address")
+	}
+	entry.HostName = vmi.Status.Interfaces[networkNum].IP
+	return entry, nil
+}
+
+// writeSSHConfig prints a Host block for each entry
+func writeSSHConfig(out io.Writer, entries []sshHostEntry) error {
+	for _, entry := range entries {
+		_, err := fmt.Fprintf(out, "Host %s\n  HostName %s\n  Port %d\n  User %s\n", entry.Alias, entry.HostName, entry.Port, entry.User)
+		if err != nil {
+			return err
+		}
+		if entry.IdentityFile != "" {
+			fmt.Fprintf(out, "  IdentityFile %s\n", quoteSSHConfigValue(entry.IdentityFile))
+		}
+		if entry.ProxyCommand != "" {
+			fmt.Fprintf(out, "  ProxyCommand %s\n", entry.ProxyCommand)
+		}
+		fmt.Fprintln(out)
+	}
+
+	return nil
+}
+
+// writeAnsibleInventory prints an Ansible YAML inventory of the entries, grouped by namespace and by label
+func writeAnsibleInventory(out io.Writer, entries []sshHostEntry) error {
+	all := &ansibleGroup{
+		Hosts:    map[string]map[string]interface{}{},
+		Children: map[string]*ansibleGroup{},
+	}
+
+	addToGroup := func(groupName string, alias string) {
+		groupName = ansibleGroupInvalidChars.ReplaceAllString(groupName, "_")
+		if all.Children[groupName] == nil {
+			all.Children[groupName] = &ansibleGroup{Hosts: map[string]map[string]interface{}{}}
+		}
+		all.Children[groupName].Hosts[alias] = map[string]interface{}{}
+	}
+
+	for _, entry := range entries {
+		hostVars := map[string]interface{}{
+			"ansible_host": entry.HostName,
+			"ansible_port": entry.Port,
+			"ansible_user": entry.User,
+		}
+		if entry.IdentityFile != "" {
+			hostVars["ansible_ssh_private_key_file"] = entry.IdentityFile
+		}
+		if entry.ProxyCommand != "" {
+			hostVars["ansible_ssh_common_args"] = fmt.Sprintf("-o ProxyCommand=\"%s\"", entry.ProxyCommand)
+		}
+		all.Hosts[entry.Alias] = hostVars
+
+		addToGroup("namespace_"+entry.Namespace, entry.Alias)
+
+		for key, value := range entry.Labels {
+			addToGroup(key+"_"+value, entry.Alias)
+		}
+	}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	err := encoder.Encode(map[string]*ansibleGroup{"all": all})
+	if err != nil {
+		return fmt.Errorf("error during encoding of the Ansible inventory: %w", err)
+	}
+	return encoder.Close()
+}
+
+// quoteSSHConfigValue quotes a value containing spaces for ssh_config
+func quoteSSHConfigValue(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func newTestVM(name string, networks ...VMv1.Network) *VMv1.VirtualMachine {
+	return &VMv1.VirtualMachine{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: VMv1.VirtualMachineSpec{
+			Template: &VMv1.VirtualMachineInstanceTemplateSpec{
+				Spec: VMv1.VirtualMachineInstanceSpec{Networks: networks},
+			},
+		},
+	}
+}
+
+func TestBuildSSHHostEntry(t *testing.T) {
+	podNetwork := VMv1.Network{Name: "default", NetworkSource: VMv1.NetworkSource{Pod: &VMv1.PodNetwork{}}}
+	bridgeNetwork := VMv1.Network{Name: "vlan", NetworkSource: VMv1.NetworkSource{Multus: &VMv1.MultusNetwork{NetworkName: "default/vlan1"}}}
+	vmi := &VMv1.VirtualMachineInstance{
+		Status: VMv1.VirtualMachineInstanceStatus{
+			Interfaces: []VMv1.VirtualMachineInstanceNetworkInterface{{IP: "10.0.0.5"}},
+		},
+	}
+
+	entry, err := buildSSHHostEntry(newTestVM("bridged", bridgeNetwork), vmi, false, "harvester vm port-forward --stdio")
+	if err != nil || entry.HostName != "10.0.0.5" || entry.ProxyCommand != "" {
+		t.Errorf("Expected bridge entry on 10.0.0.5, got %+v (%v)", entry, err)
+	}
+
+	_, err = buildSSHHostEntry(newTestVM("stopped", bridgeNetwork), nil, false, "harvester vm port-forward --stdio")
+	if err == nil {
+		t.Errorf("Expected an error for a bridged VM without IP address")
+	}
+
+	entry, err = buildSSHHostEntry(newTestVM("podvm", podNetwork), nil, false, "harvester vm port-forward --stdio")
+	expected := "harvester vm port-forward --stdio --namespace default podvm %p"
+	if err != nil || entry.HostName != "podvm.default" || entry.ProxyCommand != expected {
+		t.Errorf("Expected pod network entry with ProxyCommand %s, got %+v (%v)", expected, entry, err)
+	}
+}
+
+func TestWriteAnsibleInventory(t *testing.T) {
+	entries := []sshHostEntry{
+		{Alias: "vm1.default", HostName: "10.0.0.5", Port: 22, User: "ubuntu", Namespace: "default", Labels: map[string]string{"harvesterhci.io/os": "ubuntu"}},
+	}
+
+	var out bytes.Buffer
+	err := writeAnsibleInventory(&out, entries)
+	if err != nil {
+		t.Fatalf("Error writing inventory: %v", err)
+	}
+
+	for _, expected := range []string{"ansible_host: 10.0.0.5", "namespace_default:", "harvesterhci_io_os_ubuntu:"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in inventory:\n%s", expected, out.String())
+		}
+	}
+}
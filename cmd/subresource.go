@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/gorilla/websocket"
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	kubevirtSubresourcesPath = "/apis/subresources.kubevirt.io/v1"
+	vmResource               = "virtualmachines"
+	vmiResource              = "virtualmachineinstances"
+	plainStreamProtocol      = "plain.kubevirt.io"
+)
+
+// getRunningVMI returns the VMI of a VM, or an error if the VM is not running
+func getRunningVMI(c *harvclient.Clientset, namespace string, vmName string) (*VMv1.VirtualMachineInstance, error) {
+	vmi, err := c.KubevirtV1().VirtualMachineInstances(namespace).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("VM %s is not running: %w", vmName, err)
+	}
+	if vmi.Status.Phase != VMv1.Running {
+		return nil, fmt.Errorf("VM %s is not running, its phase is %s", vmName, vmi.Status.Phase)
+	}
+	return vmi, nil
+}
+
+// putVMSubresource calls a KubeVirt subresource of a VM or VMI, such as restart or pause, with the given options encoded in JSON as the request body
+func putVMSubresource(c *harvclient.Clientset, namespace string, resource string, name string, subresource string, options interface{}) error {
+	body := []byte("{}")
+	if options != nil {
+		var err error
+		body, err = json.Marshal(options)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.KubevirtV1().RESTClient().Put().
+		AbsPath(kubevirtSubresourcesPath, "namespaces", namespace, resource, name, subresource).
+		SetHeader("Content-Type", "application/json").
+		Body(body).
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		return fmt.Errorf("%s of %s/%s failed: %w", subresource, namespace, name, err)
+	}
+
+	return nil
+}
+
+// getVMISubresource reads a KubeVirt subresource of a VMI, such as guestosinfo, and decodes its JSON response into result
+func getVMISubresource(c *harvclient.Clientset, namespace string, name string, subresource string, result interface{}) error {
+	body, err := c.KubevirtV1().RESTClient().Get().
+		AbsPath(kubevirtSubresourcesPath, "namespaces", namespace, vmiResource, name, subresource).
+		DoRaw(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s of %s/%s failed: %w", subresource, namespace, name, err)
+	}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("error during decoding of %s of %s/%s: %w", subresource, namespace, name, err)
+	}
+
+	return nil
+}
+
+// dialVMISubresource opens a websocket to a streaming KubeVirt VMI subresource, such as console or vnc, using the Harvester KUBECONFIG for authentication
+func dialVMISubresource(ctx *cli.Context, namespace string, name string, subresource string) (*websocket.Conn, error) {
+	restConfig, err := GetRESTClientAndConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subresourceURL, err := vmiSubresourceURL(restConfig.Host, namespace, name, subresource)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error during creation of TLS configuration: %w", err)
+	}
+
+	header := http.Header{}
+	if restConfig.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+restConfig.BearerToken)
+	} else if restConfig.Username != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(restConfig.Username+":"+restConfig.Password)))
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+		Subprotocols:    []string{plainStreamProtocol},
+	}
+
+	conn, resp, err := dialer.Dial(subresourceURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("error during connection to %s of VM %s: %w (%s)", subresource, name, err, resp.Status)
+		}
+		return nil, fmt.Errorf("error during connection to %s of VM %s: %w", subresource, name, err)
+	}
+
+	return conn, nil
+}
+
+// vmiSubresourceURL builds the websocket URL of a VMI subresource, keeping the path of the API server, e.g. the Rancher cluster proxy prefix
+func vmiSubresourceURL(host string, namespace string, name string, subresource string) (string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid API server URL %s: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("unsupported scheme in API server URL %s", host)
+	}
+
+	u.Path = path.Join(u.Path, kubevirtSubresourcesPath, "namespaces", namespace, vmiResource, name, subresource)
+	return u.String(), nil
+}
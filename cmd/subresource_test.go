@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestVMISubresourceURL(t *testing.T) {
+	subresourceURL, err := vmiSubresourceURL("https://rancher.example.com/k8s/clusters/c-m-abcde", "default", "vm1", "console")
+	if err != nil {
+		t.Fatalf("Error building subresource URL: %v", err)
+	}
+
+	expected := "wss://rancher.example.com/k8s/clusters/c-m-abcde/apis/subresources.kubevirt.io/v1/namespaces/default/virtualmachineinstances/vm1/console"
+	if subresourceURL != expected {
+		t.Errorf("Expected %s, got %s", expected, subresourceURL)
+	}
+
+	_, err = vmiSubresourceURL("ftp://harvester.example.com", "default", "vm1", "console")
+	if err == nil {
+		t.Errorf("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestPutVMSubresource(t *testing.T) {
+	requests := map[string]string{}
+	c := newTestHarvesterClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s with content type %q", r.Method, r.URL, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests[r.URL.Path] = string(body)
+
+		if strings.HasSuffix(r.URL.Path, "/vm2/pause") {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+
+	tests := []struct {
+		subresource string
+		options     interface{}
+		body        string
+	}{
+		{"pause", &VMv1.PauseOptions{}, `{}`},
+		{"unpause", &VMv1.UnpauseOptions{}, `{}`},
+		{"freeze", &VMv1.FreezeUnfreezeTimeout{UnfreezeTimeout: &k8smetav1.Duration{Duration: 5 * time.Minute}}, `{"unfreezeTimeout":"5m0s"}`},
+		{"unfreeze", nil, `{}`},
+	}
+
+	for _, test := range tests {
+		err := putVMSubresource(c, "default", vmiResource, "vm1", test.subresource, test.options)
+		if err != nil {
+			t.Errorf("Error calling %s: %v", test.subresource, err)
+		}
+
+		path := "/apis/subresources.kubevirt.io/v1/namespaces/default/virtualmachineinstances/vm1/" + test.subresource
+		body, ok := requests[path]
+		if !ok || body != test.body {
+			t.Errorf("Expected a request to %s with the body %s, got %q (%t)", path, test.body, body, ok)
+		}
+	}
+
+	err := putVMSubresource(c, "default", vmiResource, "vm2", "pause", &VMv1.PauseOptions{})
+	if err == nil || !strings.HasPrefix(err.Error(), "pause of default/vm2 failed") {
+		t.Errorf("Expected the pause of vm2 to fail, got %v", err)
+	}
+}
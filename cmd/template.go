@@ -10,11 +10,11 @@ import (
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
-	rcmd "github.com/rancher/cli/cmd"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 const (
@@ -25,14 +25,20 @@ const (
 )
 
 type TemplateData struct {
-	Name       string
-	Version    int
-	Image      string
-	Cpus       uint32
-	Memory     string
-	Interfaces []Interface
-	Keypairs   []string
-	Volumes    []Volume
+	Name                   string
+	Version                int
+	Image                  string
+	Cpus                   uint32
+	Memory                 string
+	Interfaces             []Interface
+	Keypairs               []string
+	Volumes                []Volume
+	VirtualMachineTemplate v1beta1.VirtualMachineTemplate `yaml:"-"`
+}
+
+// APIObject returns the VM template the display structure was built from
+func (d TemplateData) APIObject() interface{} {
+	return d.VirtualMachineTemplate
 }
 
 type Volume struct {
@@ -67,9 +73,11 @@ func TemplateCommand() *cli.Command {
 		Aliases: []string{"tpl"},
 		Usage:   "Manipulate VM templates",
 		Action:  templateList,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&nsFlag,
-		},
+			&outputFlag,
+			&noHeadersFlag,
+		}, listFlags()...),
 		Subcommands: cli.Commands{
 			&cli.Command{
 				Name:        "list",
@@ -78,9 +86,12 @@ func TemplateCommand() *cli.Command {
 				Description: "\nLists all the VM templates available in Harvester",
 				ArgsUsage:   "None",
 				Action:      templateList,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+					&watchFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				}, listFlags()...),
 			},
 			&cli.Command{
 				Name:        "show",
@@ -91,6 +102,7 @@ func TemplateCommand() *cli.Command {
 				Action:      templateShow,
 				Flags: []cli.Flag{
 					&nsFlag,
+					&outputFlag,
 				},
 			},
 		},
@@ -105,30 +117,42 @@ func templateList(ctx *cli.Context) (err error) {
 		return
 	}
 
-	tplList, err := c.HarvesterhciV1beta1().VirtualMachineTemplates(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	namespace := listNamespace(ctx)
+	if !ctx.Bool("watch") {
+		return printTemplateList(ctx, c, namespace)
+	}
+
+	return renderOnChanges(func() error {
+		return printTemplateList(ctx, c, namespace)
+	}, func(watchCtx context.Context) (watch.Interface, error) {
+		return c.HarvesterhciV1beta1().VirtualMachineTemplates(namespace).Watch(watchCtx, listOptions(ctx))
+	})
+}
+
+// printTemplateList prints the VM templates of a namespace, or of all of them if it is empty, with their latest version
+func printTemplateList(ctx *cli.Context, c *harvclient.Clientset, namespace string) (err error) {
+	tplList, err := c.HarvesterhciV1beta1().VirtualMachineTemplates(namespace).List(context.TODO(), listOptions(ctx))
 
 	if err != nil {
 		return
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriter(ctx, withNamespaceColumn(ctx, [][]string{
 		{"NAME", "Name"},
 		{"LATEST_VERSION", "Version"},
-	},
-		ctxv1)
-
-	defer writer.Close()
+	}, "VirtualMachineTemplate.Namespace"))
 
 	for _, tplItem := range tplList.Items {
 
 		writer.Write(&TemplateData{
-			Name:    tplItem.Name,
-			Version: tplItem.Status.LatestVersion,
+			Name:                   tplItem.Name,
+			Version:                tplItem.Status.LatestVersion,
+			VirtualMachineTemplate: tplItem,
 		})
 
 	}
 
-	return writer.Err()
+	return writer.Close()
 }
 
 // templateShow prints the content of the VM template in argument given the CLI context
@@ -173,6 +197,10 @@ func templateShow(ctx *cli.Context) error {
 		return fmt.Errorf("error during querying VM Template, %w", err)
 	}
 
+	if printed, err := printObject(ctx, matchingVMTemplate); printed || err != nil {
+		return err
+	}
+
 	imageName, err := getImageName(matchingVMTemplate, c)
 
 	if err != nil {
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rancher/cli/config"
+)
+
+const (
+	defaultTransferChunkSize = "4Mi"
+	progressRefreshInterval  = 500 * time.Millisecond
+)
+
+// newHarvesterHTTPClient creates an HTTP client trusting the CA certificate stored in the Rancher server configuration, on top of the system CAs
+func newHarvesterHTTPClient(serverConfig *config.ServerConfig) (*http.Client, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	if serverConfig.CACerts != "" {
+		pemBlock, _ := pem.Decode([]byte(serverConfig.CACerts))
+		if pemBlock == nil {
+			return nil, fmt.Errorf("invalid CA certification in Rancher configuration, no PEM block found")
+		}
+		ownCert, err := x509.ParseCertificate(pemBlock.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA certification in Rancher configuration, %w", err)
+		}
+		rootCAs.AddCert(ownCert)
+	}
+
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			RootCAs: rootCAs,
+		},
+	}
+	return &http.Client{Transport: tr}, nil
+}
+
+// progressReader wraps an io.Reader and periodically prints how many bytes went through it
+type progressReader struct {
+	reader     io.Reader
+	out        io.Writer
+	label      string
+	total      int64
+	current    int64
+	lastUpdate time.Time
+}
+
+// newProgressReader returns a progressReader starting at offset bytes out of total, a total of 0 or less means unknown size
+func newProgressReader(reader io.Reader, out io.Writer, label string, offset int64, total int64) *progressReader {
+	return &progressReader{
+		reader:  reader,
+		out:     out,
+		label:   label,
+		total:   total,
+		current: offset,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.current += int64(n)
+	if time.Since(p.lastUpdate) >= progressRefreshInterval || err == io.EOF {
+		p.print()
+		p.lastUpdate = time.Now()
+	}
+	return n, err
+}
+
+func (p *progressReader) print() {
+	if p.out == nil {
+		return
+	}
+	if p.total > 0 {
+		fmt.Fprintf(p.out, "\r%s: %3d%% (%s / %s)", p.label, p.current*100/p.total, humanBytes(p.current), humanBytes(p.total))
+	} else {
+		fmt.Fprintf(p.out, "\r%s: %s", p.label, humanBytes(p.current))
+	}
+}
+
+// Done terminates the progress line
+func (p *progressReader) Done() {
+	if p.out == nil {
+		return
+	}
+	p.print()
+	fmt.Fprintln(p.out)
+}
+
+// humanBytes formats a number of bytes using binary units
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
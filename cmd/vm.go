@@ -7,18 +7,21 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
 	"github.com/minio/pkg/wildcard"
-	rcmd "github.com/rancher/cli/cmd"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	k8sresource "k8s.io/apimachinery/pkg/api/resource"
 	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	VMv1 "kubevirt.io/api/core/v1"
 )
 
@@ -35,6 +38,8 @@ const (
 	defaultCloudInitCmPrefix     = "default-ubuntu-"
 	defaultOverCommitSettingName = "overcommit-config"
 	RemovedPVCsAnnotationKey     = "harvesterhci.io/removedPersistentVolumeClaims"
+	defaultVMWaitTimeout         = 10 * time.Minute
+	defaultUnfreezeTimeout       = 5 * time.Minute
 )
 
 var (
@@ -56,6 +61,12 @@ type VirtualMachineData struct {
 	CPU            uint32
 	Memory         string
 	IPAddress      string
+	IPAddresses    string
+}
+
+// APIObject returns the VM the display structure was built from
+func (d VirtualMachineData) APIObject() interface{} {
+	return d.VirtualMachine
 }
 
 // VMCommand defines the CLI command that manages VMs
@@ -73,9 +84,12 @@ func VMCommand() *cli.Command {
 				Description: "\nList all VMs in the current Harvester Cluster",
 				ArgsUsage:   "None",
 				Action:      vmLs,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+					&watchFlag,
+					&outputFlag,
+					&noHeadersFlag,
+				}, listFlags()...),
 			},
 			{
 				Name: "delete",
@@ -83,12 +97,13 @@ func VMCommand() *cli.Command {
 					"del",
 					"rm",
 				},
-				Usage:     "Delete a VM",
-				Action:    vmDelete,
-				ArgsUsage: "[VM_NAME/VM_ID]",
-				Flags: []cli.Flag{
+				Usage:       "Delete VMs",
+				Description: "\nDeletes the VMs given as arguments, which may contain wildcards, or matching the selector" + bulkDescription,
+				Action:      vmDelete,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+				}, bulkFlags()...),
 			},
 			{
 				Name: "create",
@@ -98,7 +113,7 @@ func VMCommand() *cli.Command {
 				Usage:     "Create a VM",
 				Action:    vmCreate,
 				ArgsUsage: "[VM_NAME]",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&nsFlag,
 					&cli.StringFlag{
 						Name:    "vm-description",
@@ -189,44 +204,103 @@ func VMCommand() *cli.Command {
 						EnvVars: []string{"HARVESTER_VM_NETWORK"},
 						Value:   "",
 					},
-				},
+				}, waitFlags(vmWaitRunning)...),
 			},
 			{
-				Name:      "stop",
-				Usage:     "Stop a VM",
-				Action:    vmStop,
-				ArgsUsage: "[VM_NAME]",
-				Flags: []cli.Flag{
+				Name:        "stop",
+				Usage:       "Stop VMs",
+				Description: "\nStops the VMs given as arguments, which may contain wildcards, or matching the selector" + bulkDescription,
+				Action:      vmStop,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append(append([]cli.Flag{
 					&nsFlag,
-				},
+				}, waitFlags(vmWaitStopped)...), bulkFlags()...),
 			},
 			{
-				Name:      "start",
-				Usage:     "Start a VM",
-				Action:    vmStart,
-				ArgsUsage: "[VM_NAME]",
-				Flags: []cli.Flag{
+				Name:        "start",
+				Usage:       "Start VMs",
+				Description: "\nStarts the VMs given as arguments, which may contain wildcards, or matching the selector" + bulkDescription,
+				Action:      vmStart,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append(append([]cli.Flag{
 					&nsFlag,
-				},
+				}, waitFlags(vmWaitRunning)...), bulkFlags()...),
 			},
 			{
-				Name:      "restart",
-				Usage:     "Restart a VM",
-				Action:    vmRestart,
-				ArgsUsage: "[VM_NAME]",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:  "vm-name, name",
-						Usage: "Name of the VM to restart",
+				Name:        "restart",
+				Usage:       "Restart VMs",
+				Description: "\nRestarts the VMs given as arguments, which may contain wildcards, or matching the selector, by recreating their VMI or, with --soft, by rebooting the guest through the guest agent. Stopped VMs are started" + bulkDescription,
+				Action:      vmRestart,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append(append([]cli.Flag{
+					&nsFlag,
+					&cli.BoolFlag{
+						Name:  "soft",
+						Usage: "Reboot the guest OS through the QEMU guest agent instead of recreating the VMI, cannot be used with --wait",
 					},
+				}, waitFlags(vmWaitRunning)...), bulkFlags()...),
+			},
+			{
+				Name:        "pause",
+				Usage:       "Pause VMs",
+				Description: "\nPauses the VMs given as arguments, which may contain wildcards, or matching the selector" + bulkDescription,
+				Action:      vmPause,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append([]cli.Flag{
 					&nsFlag,
-				},
+				}, bulkFlags()...),
+			},
+			{
+				Name:        "unpause",
+				Usage:       "Unpause VMs",
+				Description: "\nUnpauses the VMs given as arguments, which may contain wildcards, or matching the selector" + bulkDescription,
+				Action:      vmUnpause,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append([]cli.Flag{
+					&nsFlag,
+				}, bulkFlags()...),
 			},
+			{
+				Name:  "freeze",
+				Usage: "Freeze the filesystems of VMs",
+				Description: "\nFreezes the filesystems of the VMs given as arguments, which may contain wildcards, or matching the selector, through the QEMU guest agent, " +
+					"they are unfrozen automatically after --unfreeze-timeout" + bulkDescription,
+				Action:    vmFreeze,
+				ArgsUsage: "[VM_NAME...]",
+				Flags: append([]cli.Flag{
+					&nsFlag,
+					&cli.DurationFlag{
+						Name:  "unfreeze-timeout",
+						Usage: "Duration after which the filesystems are unfrozen automatically, 0 to never unfreeze them automatically",
+						Value: defaultUnfreezeTimeout,
+					},
+				}, bulkFlags()...),
+			},
+			{
+				Name:        "unfreeze",
+				Usage:       "Unfreeze the filesystems of VMs",
+				Description: "\nUnfreezes the filesystems of the VMs given as arguments, which may contain wildcards, or matching the selector, through the QEMU guest agent" + bulkDescription,
+				Action:      vmUnfreeze,
+				ArgsUsage:   "[VM_NAME...]",
+				Flags: append([]cli.Flag{
+					&nsFlag,
+				}, bulkFlags()...),
+			},
+			vmSnapshotCommand(),
+			vmBackupCommand(),
+			vmMigrateCommand(),
+			vmMigrationsCommand(),
+			vmConsoleCommand(),
+			vmVNCCommand(),
+			vmPortForwardCommand(),
+			vmGuestInfoCommand(),
+			vmDescribeCommand(),
+			vmWaitCommand(),
 		},
 	}
 }
 
-// vmLs lists the VMs available in Harvester
+// vmLs lists the VMs available in Harvester, and keeps listing them when they change if --watch is set
 func vmLs(ctx *cli.Context) error {
 
 	c, err := GetHarvesterClient(ctx)
@@ -235,13 +309,30 @@ func vmLs(ctx *cli.Context) error {
 		return err
 	}
 
-	vmList, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	namespace := listNamespace(ctx)
+	if !ctx.Bool("watch") {
+		return printVMList(ctx, c, namespace)
+	}
+
+	return renderOnChanges(func() error {
+		return printVMList(ctx, c, namespace)
+	}, func(watchCtx context.Context) (watch.Interface, error) {
+		return c.KubevirtV1().VirtualMachines(namespace).Watch(watchCtx, listOptions(ctx))
+	}, func(watchCtx context.Context) (watch.Interface, error) {
+		return c.KubevirtV1().VirtualMachineInstances(namespace).Watch(watchCtx, k8smetav1.ListOptions{})
+	})
+}
+
+// printVMList prints the VMs of a namespace, or of all of them if it is empty, with the state of their VMI
+// the selectors only apply to the VMs, the VMIs do not have the same labels
+func printVMList(ctx *cli.Context, c *harvclient.Clientset, namespace string) error {
+	vmList, err := c.KubevirtV1().VirtualMachines(namespace).List(context.TODO(), listOptions(ctx))
 
 	if err != nil {
 		return err
 	}
 
-	vmiList, err := c.KubevirtV1().VirtualMachineInstances(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+	vmiList, err := c.KubevirtV1().VirtualMachineInstances(namespace).List(context.TODO(), k8smetav1.ListOptions{})
 
 	if err != nil {
 		return err
@@ -249,55 +340,52 @@ func vmLs(ctx *cli.Context) error {
 
 	vmiMap := map[string]VMv1.VirtualMachineInstance{}
 	for _, vmi := range vmiList.Items {
-		vmiMap[vmi.Name] = vmi
+		vmiMap[vmi.Namespace+"/"+vmi.Name] = vmi
 	}
 
-	writer := rcmd.NewTableWriter([][]string{
+	writer := newTableWriter(ctx, withNamespaceColumn(ctx, [][]string{
 		{"STATE", "State"},
 		{"NAME", "Name"},
 		{"NODE", "Node"},
 		{"CPU", "CPU"},
 		{"RAM", "Memory"},
 		{"IP Address", "IPAddress"},
-	},
-		ctxv1)
-
-	defer writer.Close()
+	}, "VirtualMachine.Namespace"),
+		[]string{"IP ADDRESSES", "IPAddresses"})
 
 	for _, vm := range vmList.Items {
 
 		state := string(vm.Status.PrintableStatus)
+		vmi := vmiMap[vm.Namespace+"/"+vm.Name]
 
 		var IP string
-		if vmiMap[vm.Name].Status.Interfaces == nil {
+		var IPs []string
+		if vmi.Status.Interfaces == nil {
 			IP = ""
 		} else {
-			IP = vmiMap[vm.Name].Status.Interfaces[0].IP
-		}
-
-		var memory string
-		if vm.Spec.Template.Spec.Domain.Resources.Limits.Memory().CmpInt64(int64(0)) == 0 {
-			memory = vm.Spec.Template.Spec.Domain.Resources.Requests.Memory().String()
-		} else {
-			memory = vm.Spec.Template.Spec.Domain.Resources.Limits.Memory().String()
+			IP = vmi.Status.Interfaces[0].IP
+			for _, iface := range vmi.Status.Interfaces {
+				IPs = append(IPs, interfaceIPs(iface)...)
+			}
 		}
 
 		writer.Write(&VirtualMachineData{
 			State:          state,
 			VirtualMachine: vm,
 			Name:           vm.Name,
-			Node:           vmiMap[vm.Name].Status.NodeName,
+			Node:           vmi.Status.NodeName,
 			CPU:            vm.Spec.Template.Spec.Domain.CPU.Cores,
-			Memory:         memory,
+			Memory:         vmMemory(&vm),
 			IPAddress:      IP,
+			IPAddresses:    strings.Join(IPs, ","),
 		})
 
 	}
 
-	return writer.Err()
+	return writer.Close()
 }
 
-// vmDelete deletes VMs which name is given in argument
+// vmDelete deletes the VMs which names are given in argument, or which match the selector
 func vmDelete(ctx *cli.Context) error {
 	c, err := GetHarvesterClient(ctx)
 
@@ -305,34 +393,9 @@ func vmDelete(ctx *cli.Context) error {
 		return err
 	}
 
-	for _, vmName := range ctx.Args().Slice() {
-
-		if strings.Contains(vmName, "*") || strings.Contains(vmName, "?") {
-			matchingVMs := buildVMListMatchingWildcard(c, ctx, vmName)
-
-			for _, vmExisting := range matchingVMs {
-
-				err := vmDeleteWithPVC(&vmExisting, c, ctx)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			vm, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
-
-			if err != nil {
-				return fmt.Errorf("no VM with the provided name found")
-			}
-
-			err = vmDeleteWithPVC(vm, c, ctx)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-
+	return runVMOperation(ctx, c, "delete", func(vm *VMv1.VirtualMachine) error {
+		return vmDeleteWithPVC(vm, c, ctx)
+	})
 }
 
 func vmDeleteWithPVC(vmExisting *VMv1.VirtualMachine, c *harvclient.Clientset, ctx *cli.Context) error {
@@ -350,14 +413,17 @@ func vmDeleteWithPVC(vmExisting *VMv1.VirtualMachine, c *harvclient.Clientset, c
 		}
 	}
 
+	if vmCopy.Annotations == nil {
+		vmCopy.Annotations = map[string]string{}
+	}
 	vmCopy.Annotations[RemovedPVCsAnnotationKey] = strings.Join(removedPVCs, ",")
-	_, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Update(context.TODO(), vmCopy, k8smetav1.UpdateOptions{})
+	_, err := c.KubevirtV1().VirtualMachines(vmCopy.Namespace).Update(context.TODO(), vmCopy, k8smetav1.UpdateOptions{})
 
 	if err != nil {
 		return fmt.Errorf("error during removal of PVCs in the VM reference, %w", err)
 	}
 
-	err = c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Delete(context.TODO(), vmCopy.Name, k8smetav1.DeleteOptions{})
+	err = c.KubevirtV1().VirtualMachines(vmCopy.Namespace).Delete(context.TODO(), vmCopy.Name, k8smetav1.DeleteOptions{})
 	if err != nil {
 		return fmt.Errorf("VM named %s could not be deleted successfully: %w", vmCopy.Name, err)
 	} else {
@@ -567,6 +633,7 @@ func vmCreateFromImage(ctx *cli.Context, c *harvclient.Clientset, vmTemplate *VM
 	}
 	ctx.App.Metadata["overCommitSettingMap"] = overCommitSettingMap
 
+	var createdVMNames []string
 	for i := 1; i <= ctx.Int("count"); i++ {
 		var vmName string
 		if ctx.Int("count") > 1 {
@@ -651,6 +718,17 @@ func vmCreateFromImage(ctx *cli.Context, c *harvclient.Clientset, vmTemplate *VM
 		if err != nil {
 			return err
 		}
+		createdVMNames = append(createdVMNames, vmName)
+	}
+
+	if ctx.Bool("wait") {
+		for _, vmName := range createdVMNames {
+			err = waitForVMCondition(c, ctx.String("namespace"), vmName, vmWaitRunning, ctx.Duration("timeout"))
+			if err != nil {
+				return err
+			}
+			logrus.Infof("VM %s is Running", vmName)
+		}
 	}
 
 	return nil
@@ -820,7 +898,7 @@ func buildVMTemplate(ctx *cli.Context, c *harvclient.Clientset,
 	return
 }
 
-// vmStart issues a power on for the virtual machine instances which names are given as argument to the start command.
+// vmStart issues a power on for the virtual machine instances which names are given as argument to the start command, or which match the selector.
 func vmStart(ctx *cli.Context) error {
 
 	c, err := GetHarvesterClient(ctx)
@@ -828,32 +906,22 @@ func vmStart(ctx *cli.Context) error {
 		return err
 	}
 
-	for _, vmName := range ctx.Args().Slice() {
-
-		if strings.Contains(vmName, "*") || strings.Contains(vmName, "?") {
-			matchingVMs := buildVMListMatchingWildcard(c, ctx, vmName)
-
-			for _, vmNameExisting := range matchingVMs {
-				err = startVMbyRef(c, ctx, vmNameExisting)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			return startVMbyName(c, ctx, vmName)
-
-		}
-	}
+	return runVMOperation(ctx, c, "start", func(vm *VMv1.VirtualMachine) error {
+		return startVMbyRef(c, ctx, vm)
+	})
+}
 
-	return nil
+// buildVMListMatchingWildcard creates an array of VM objects which names match the given wildcard pattern, in all the namespaces if --all-namespaces is set
+func buildVMListMatchingWildcard(c *harvclient.Clientset, ctx *cli.Context, vmNameWildcard string) ([]VMv1.VirtualMachine, error) {
+	return listVMsMatchingWildcard(c, listNamespace(ctx), vmNameWildcard)
 }
 
-// buildVMListMatchingWildcard creates an array of VM objects which names match the given wildcard pattern
-func buildVMListMatchingWildcard(c *harvclient.Clientset, ctx *cli.Context, vmNameWildcard string) []VMv1.VirtualMachine {
-	vms, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).List(context.TODO(), k8smetav1.ListOptions{})
+// listVMsMatchingWildcard lists the VMs of a namespace, or of all the namespaces if it is empty, which names match the given wildcard pattern
+func listVMsMatchingWildcard(c *harvclient.Clientset, namespace string, vmNameWildcard string) ([]VMv1.VirtualMachine, error) {
+	vms, err := c.KubevirtV1().VirtualMachines(namespace).List(context.TODO(), k8smetav1.ListOptions{})
 
 	if err != nil {
-		logrus.Warnf("No VMs found with name %s", vmNameWildcard)
+		return nil, fmt.Errorf("error during listing of VMs matching %s: %w", vmNameWildcard, err)
 	}
 
 	var matchingVMs []VMv1.VirtualMachine
@@ -865,96 +933,182 @@ func buildVMListMatchingWildcard(c *harvclient.Clientset, ctx *cli.Context, vmNa
 		}
 	}
 	logrus.Infof("number of matching VMs for pattern %s: %d", vmNameWildcard, len(matchingVMs))
-	return matchingVMs
+	return matchingVMs, nil
 }
 
-// startVMbyName starts a VM by first issuing a GET using the VM name, then updating the resulting VM object
-func startVMbyName(c *harvclient.Clientset, ctx *cli.Context, vmName string) error {
-	vm, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+// startVMbyRef updates a VM object to make it Running
+func startVMbyRef(c *harvclient.Clientset, ctx *cli.Context, vm *VMv1.VirtualMachine) error {
+	running := true
+	vm.Spec.Running = &running
 
+	_, err := c.KubevirtV1().VirtualMachines(vm.Namespace).Update(context.TODO(), vm, k8smetav1.UpdateOptions{})
 	if err != nil {
-		err1 := fmt.Errorf("vm with provided name not found: %w", err)
-		logrus.Errorf("No VM named %s was not found (%s) the subsequent VMs will not be started!", vmName, err)
-		return err1
+		return fmt.Errorf("error while starting VM %s: %w", vm.Name, err)
 	}
+	logrus.Infof("VM %s started successfully", vm.Name)
 
-	return startVMbyRef(c, ctx, *vm)
+	if ctx.Bool("wait") {
+		return waitForVMCondition(c, vm.Namespace, vm.Name, vmWaitRunning, ctx.Duration("timeout"))
+	}
+	return nil
 }
 
-// startVMbyRef updates a VM object to make it Running
-func startVMbyRef(c *harvclient.Clientset, ctx *cli.Context, vm VMv1.VirtualMachine) (err error) {
+// vmStop issues a power off for the virtual machine instances which names are given as argument, or which match the selector.
+func vmStop(ctx *cli.Context) error {
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
 
-	*vm.Spec.Running = true
+	return runVMOperation(ctx, c, "stop", func(vm *VMv1.VirtualMachine) error {
+		return stopVMbyRef(c, ctx, vm)
+	})
+}
 
-	_, err = c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Update(context.TODO(), &vm, k8smetav1.UpdateOptions{})
+// stopVMbyRef will stop a VM by updating Spec.Running field of the VM object
+func stopVMbyRef(c *harvclient.Clientset, ctx *cli.Context, vm *VMv1.VirtualMachine) error {
+	running := false
+	vm.Spec.Running = &running
 
+	_, err := c.KubevirtV1().VirtualMachines(vm.Namespace).Update(context.TODO(), vm, k8smetav1.UpdateOptions{})
 	if err != nil {
-		logrus.Warnf("An error happened while starting VM %s: %s", vm.Name, err)
-	} else {
-		logrus.Infof("VM %s started successfully", vm.Name)
+		return fmt.Errorf("error while stopping VM %s: %w", vm.Name, err)
+	}
+	logrus.Infof("VM %s stopped successfully", vm.Name)
+
+	if ctx.Bool("wait") {
+		return waitForVMCondition(c, vm.Namespace, vm.Name, vmWaitStopped, ctx.Duration("timeout"))
 	}
 	return nil
 }
 
-// vmStop issues a power off for the virtual machine instances which name is given as argument.
-func vmStop(ctx *cli.Context) error {
+// vmRestart restarts the VMs given as arguments or matching the selector through the KubeVirt restart subresource, or softreboot subresource when --soft is set
+func vmRestart(ctx *cli.Context) error {
+	err := checkRestartFlags(ctx.Bool("soft"), ctx.Bool("wait"))
+	if err != nil {
+		return err
+	}
 
 	c, err := GetHarvesterClient(ctx)
 	if err != nil {
 		return err
 	}
-	for _, vmName := range ctx.Args().Slice() {
 
-		if strings.Contains(vmName, "*") || strings.Contains(vmName, "?") {
-			matchingVMs := buildVMListMatchingWildcard(c, ctx, vmName)
+	return runVMOperation(ctx, c, "restart", func(vm *VMv1.VirtualMachine) error {
+		return restartVMbyRef(c, ctx, vm)
+	})
+}
 
-			for _, vmExisting := range matchingVMs {
-				err = stopVMbyRef(c, ctx, &vmExisting)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			return stopVMbyName(c, ctx, vmName)
-		}
+// checkRestartFlags rejects --wait with --soft, a soft reboot happens inside the guest and the VMI stays Running, so there is nothing to wait for
+func checkRestartFlags(soft bool, wait bool) error {
+	if soft && wait {
+		return fmt.Errorf("the flags --soft and --wait cannot be used together, a soft reboot does not recreate the VMI")
 	}
-	return err
+	return nil
 }
 
-// stopVMbyName will stop a VM by first finding it by its name and then call stopBMbyRef function
-func stopVMbyName(c *harvclient.Clientset, ctx *cli.Context, vmName string) error {
-	vm, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Get(context.TODO(), vmName, k8smetav1.GetOptions{})
+// restartVMbyRef restarts a running VM, and waits for its new VMI to be Running if --wait is set.
+// A VM without VMI is stopped, so it is started instead.
+func restartVMbyRef(c *harvclient.Clientset, ctx *cli.Context, vm *VMv1.VirtualMachine) error {
+	vmi, err := c.KubevirtV1().VirtualMachineInstances(vm.Namespace).Get(context.TODO(), vm.Name, k8smetav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		logrus.Infof("VM %s is not running, starting it", vm.Name)
+		return startVMbyRef(c, ctx, vm)
+	}
+	if err != nil {
+		return fmt.Errorf("error during fetching of VMI %s: %w", vm.Name, err)
+	}
+
+	if ctx.Bool("soft") {
+		err = putVMSubresource(c, vm.Namespace, vmiResource, vm.Name, "softreboot", nil)
+	} else {
+		err = putVMSubresource(c, vm.Namespace, vmResource, vm.Name, "restart", &VMv1.RestartOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	logrus.Infof("VM %s restart requested", vm.Name)
 
+	if !ctx.Bool("wait") {
+		return nil
+	}
+
+	err = waitForVMIRunning(c, vm.Namespace, vm.Name, vmi.UID, ctx.Duration("timeout"))
 	if err != nil {
-		err1 := fmt.Errorf("vm with provided name not found: %s", err)
-		logrus.Errorf("No VM named %s was not found (%s) the subsequent VMs will not be stopped!", vmName, err)
-		return err1
+		return err
 	}
+	logrus.Infof("VM %s is Running", vm.Name)
 
-	return stopVMbyRef(c, ctx, vm)
+	return nil
 }
 
-// stopVMbyRef will stop a VM by updating Spec.Running field of the VM object
-func stopVMbyRef(c *harvclient.Clientset, ctx *cli.Context, vm *VMv1.VirtualMachine) error {
-	*vm.Spec.Running = false
+// waitForVMIRunning waits until a new VMI of a VM is Running, ignoring the previous VMI with UID previousUID
+func waitForVMIRunning(c *harvclient.Clientset, namespace string, name string, previousUID types.UID, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	_, err := c.KubevirtV1().VirtualMachines(ctx.String("namespace")).Update(context.TODO(), vm, k8smetav1.UpdateOptions{})
+	watcher, err := c.KubevirtV1().VirtualMachineInstances(namespace).Watch(timeoutCtx, k8smetav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
 	if err != nil {
-		logrus.Warnf("An error happened while stopping VM %s: %s", vm.Name, err)
-	} else {
-		logrus.Infof("VM %s stopped successfully", vm.Name)
+		return fmt.Errorf("error during watching VMI %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out after %s waiting for VM %s to be Running", timeout, name)
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch on VMI %s was closed before it was Running", name)
+			}
+
+			vmi, ok := event.Object.(*VMv1.VirtualMachineInstance)
+			if !ok || event.Type == watch.Deleted || vmi.UID == previousUID {
+				continue
+			}
+
+			if vmi.Status.Phase == VMv1.Running {
+				return nil
+			}
+		}
 	}
-	return nil
 }
 
-// Restart reboots virtual machine instances by calling successively vmStop and vmStart
-func vmRestart(ctx *cli.Context) error {
+// vmPause pauses the VMIs of the VMs given as arguments or matching the selector
+func vmPause(ctx *cli.Context) error {
+	return runVMISubresourceOperation(ctx, "pause", &VMv1.PauseOptions{})
+}
+
+// vmUnpause unpauses the VMIs of the VMs given as arguments or matching the selector
+func vmUnpause(ctx *cli.Context) error {
+	return runVMISubresourceOperation(ctx, "unpause", &VMv1.UnpauseOptions{})
+}
 
-	err := vmStop(ctx)
+// vmFreeze freezes the filesystems of the VMs given as arguments or matching the selector through the guest agent
+func vmFreeze(ctx *cli.Context) error {
+	return runVMISubresourceOperation(ctx, "freeze", &VMv1.FreezeUnfreezeTimeout{
+		UnfreezeTimeout: &k8smetav1.Duration{Duration: ctx.Duration("unfreeze-timeout")},
+	})
+}
+
+// vmUnfreeze unfreezes the filesystems of the VMs given as arguments or matching the selector through the guest agent
+func vmUnfreeze(ctx *cli.Context) error {
+	return runVMISubresourceOperation(ctx, "unfreeze", nil)
+}
+
+// runVMISubresourceOperation calls a KubeVirt VMI subresource for each VM given as argument or matching the selector, like the other bulk operations
+func runVMISubresourceOperation(ctx *cli.Context, subresource string, options interface{}) error {
+	c, err := GetHarvesterClient(ctx)
 	if err != nil {
 		return err
 	}
-	return vmStart(ctx)
+
+	return runVMOperation(ctx, c, subresource, func(vm *VMv1.VirtualMachine) error {
+		return putVMSubresource(c, vm.Namespace, vmiResource, vm.Name, subresource, options)
+	})
 }
 
 // vmiAnnotations generates a map of strings to be injected as annotations from a PVC name and an SSK Keyname
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestCheckRestartFlags(t *testing.T) {
+	tests := []struct {
+		soft      bool
+		wait      bool
+		expectErr bool
+	}{
+		{false, false, false},
+		{false, true, false},
+		{true, false, false},
+		{true, true, true},
+	}
+
+	for _, test := range tests {
+		err := checkRestartFlags(test.soft, test.wait)
+		if (err != nil) != test.expectErr {
+			t.Errorf("Expected an error %t with --soft=%t and --wait=%t, got %v", test.expectErr, test.soft, test.wait, err)
+		}
+	}
+}
+
+func TestRestartVMbyRefStartsStoppedVM(t *testing.T) {
+	var updated *VMv1.VirtualMachine
+	c := newTestHarvesterClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/kubevirt.io/v1/namespaces/default/virtualmachineinstances/vm1":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			writeTestJSON(t, w, &k8smetav1.Status{
+				TypeMeta: k8smetav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   k8smetav1.StatusFailure,
+				Reason:   k8smetav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+			})
+		case r.Method == http.MethodPut && r.URL.Path == "/apis/kubevirt.io/v1/namespaces/default/virtualmachines/vm1":
+			updated = &VMv1.VirtualMachine{}
+			if err := json.NewDecoder(r.Body).Decode(updated); err != nil {
+				t.Errorf("Error decoding VM update: %v", err)
+			}
+			writeTestJSON(t, w, updated)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+
+	flags := flag.NewFlagSet("restart", flag.ContinueOnError)
+	flags.Bool("soft", false, "")
+	flags.Bool("wait", false, "")
+	ctx := cli.NewContext(cli.NewApp(), flags, nil)
+
+	running := false
+	vm := &VMv1.VirtualMachine{
+		TypeMeta:   k8smetav1.TypeMeta{Kind: "VirtualMachine", APIVersion: "kubevirt.io/v1"},
+		ObjectMeta: k8smetav1.ObjectMeta{Name: "vm1", Namespace: "default"},
+		Spec:       VMv1.VirtualMachineSpec{Running: &running},
+	}
+
+	err := restartVMbyRef(c, ctx, vm)
+	if err != nil {
+		t.Fatalf("Error restarting a stopped VM: %v", err)
+	}
+	if updated == nil || updated.Spec.Running == nil || !*updated.Spec.Running {
+		t.Errorf("Expected the stopped VM to be started, got %+v", updated)
+	}
+}
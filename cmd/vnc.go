@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	defaultVNCViewer      = "vncviewer"
+	vncViewerAddressToken = "{}"
+)
+
+// vmVNCCommand defines the CLI sub-command `vm vnc` that exposes the graphical console of a VM on a local port
+func vmVNCCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "vnc",
+		Usage:       "Expose the graphical console of a VM to a local VNC viewer",
+		Description: "\nListens on a local TCP port and forwards each connection to the VNC console of the VM given as argument, until interrupted",
+		ArgsUsage:   "VM_NAME",
+		Action:      vmVNC,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.IntFlag{
+				Name:    "port",
+				Usage:   "Local port to listen on, 0 picks a free port",
+				EnvVars: []string{"HARVESTER_VNC_PORT"},
+				Value:   0,
+			},
+			&cli.BoolFlag{
+				Name:  "launch",
+				Usage: "Launch the VNC viewer and stop the proxy when it exits",
+			},
+			&cli.StringFlag{
+				Name: "viewer",
+				Usage: "Command launching the VNC viewer, " + vncViewerAddressToken + " is replaced by the local address, which is appended otherwise, " +
+					"overrides vnc.viewer of the CLI configuration file, defaults to " + defaultVNCViewer,
+				EnvVars: []string{"HARVESTER_VNC_VIEWER"},
+			},
+		},
+	}
+}
+
+// vmVNC implements the `vm vnc` command
+func vmVNC(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("one and only one argument is accepted for this command, and that is the vm name")
+	}
+
+	vmName := ctx.Args().First()
+	namespace := ctx.String("namespace")
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = getRunningVMI(c, namespace, vmName)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(ctx.Int("port"))))
+	if err != nil {
+		return fmt.Errorf("unable to listen on local port %d: %w", ctx.Int("port"), err)
+	}
+	defer listener.Close()
+
+	address := listener.Addr().String()
+	logrus.Infof("VNC console of VM %s available on %s, press Ctrl+C to stop", vmName, address)
+
+	go serveVMISubresource(listener, "vnc", func() (*websocket.Conn, error) {
+		return dialVMISubresource(ctx, namespace, vmName, "vnc")
+	})
+
+	stop := make(chan error, 1)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		<-sigs
+		stop <- nil
+	}()
+
+	if ctx.Bool("launch") {
+		cliConfig, err := loadCLIConfig(ctx)
+		if err != nil {
+			return err
+		}
+
+		viewer, err := launchVNCViewer(vncViewerCommand(ctx.String("viewer"), cliConfig), address)
+		if err != nil {
+			return err
+		}
+		go func() {
+			stop <- viewer.Wait()
+		}()
+	}
+
+	return <-stop
+}
+
+// serveVMISubresource accepts the connections on a local listener until it is closed, and forwards each of them to a new connection to a VMI subresource opened by dial
+func serveVMISubresource(listener net.Listener, subresource string, dial func() (*websocket.Conn, error)) {
+	for {
+		tcpConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go proxyVMISubresourceConnection(tcpConn, subresource, dial)
+	}
+}
+
+// proxyVMISubresourceConnection forwards a local TCP connection to a streaming subresource of a VMI, errors are only logged as they concern a single connection
+func proxyVMISubresourceConnection(tcpConn net.Conn, subresource string, dial func() (*websocket.Conn, error)) {
+	defer tcpConn.Close()
+
+	wsConn, err := dial()
+	if err != nil {
+		logrus.Warnf("Unable to open %s: %v", subresource, err)
+		return
+	}
+	defer wsConn.Close()
+
+	logrus.Infof("Connection from %s to %s opened", tcpConn.RemoteAddr(), subresource)
+
+	done := make(chan error, 2)
+	go func() {
+		done <- copyReaderToWebsocket(wsConn, tcpConn)
+	}()
+	go func() {
+		done <- copyWebsocketToWriter(tcpConn, wsConn)
+	}()
+
+	err = <-done
+	if err != nil {
+		logrus.Warnf("Connection from %s to %s closed: %v", tcpConn.RemoteAddr(), subresource, err)
+		return
+	}
+	logrus.Infof("Connection from %s to %s closed", tcpConn.RemoteAddr(), subresource)
+}
+
+// copyReaderToWebsocket sends everything read from a reader as binary messages on a websocket until the reader is closed
+func copyReaderToWebsocket(conn *websocket.Conn, in io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n])
+			if writeErr != nil {
+				return fmt.Errorf("error during writing to the websocket: %w", writeErr)
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// vncViewerCommand returns the command launching the VNC viewer, given by --viewer, by the CLI configuration file or the default one
+func vncViewerCommand(viewerFlag string, cliConfig *CLIConfig) string {
+	if viewerFlag != "" {
+		return viewerFlag
+	}
+	if cliConfig != nil && cliConfig.VNC.Viewer != "" {
+		return cliConfig.VNC.Viewer
+	}
+	return defaultVNCViewer
+}
+
+// vncViewerArgs splits the VNC viewer command into arguments, replacing the address token by the local address or appending it
+func vncViewerArgs(viewer string, address string) ([]string, error) {
+	args := strings.Fields(viewer)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no VNC viewer configured, please set --viewer or vnc.viewer in the CLI configuration file")
+	}
+
+	if strings.Contains(viewer, vncViewerAddressToken) {
+		for i := range args {
+			args[i] = strings.ReplaceAll(args[i], vncViewerAddressToken, address)
+		}
+	} else {
+		args = append(args, address)
+	}
+
+	return args, nil
+}
+
+// launchVNCViewer starts the VNC viewer command connecting to the local address
+func launchVNCViewer(viewer string, address string) (*exec.Cmd, error) {
+	args, err := vncViewerArgs(viewer, address)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("error during launch of VNC viewer %s: %w", args[0], err)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestVNCViewerCommand(t *testing.T) {
+	cliConfig := &CLIConfig{VNC: VNCConfig{Viewer: "remote-viewer vnc://{}"}}
+
+	if viewer := vncViewerCommand("tigervnc", cliConfig); viewer != "tigervnc" {
+		t.Errorf("Expected --viewer to override the configuration file, got %s", viewer)
+	}
+	if viewer := vncViewerCommand("", cliConfig); viewer != "remote-viewer vnc://{}" {
+		t.Errorf("Expected the viewer of the configuration file, got %s", viewer)
+	}
+	if viewer := vncViewerCommand("", &CLIConfig{}); viewer != defaultVNCViewer {
+		t.Errorf("Expected the default viewer, got %s", viewer)
+	}
+}
+
+func TestVNCViewerArgs(t *testing.T) {
+	cases := map[string][]string{
+		"vncviewer":                     {"vncviewer", "127.0.0.1:5901"},
+		"vncviewer -Shared":             {"vncviewer", "-Shared", "127.0.0.1:5901"},
+		"remote-viewer vnc://{}":        {"remote-viewer", "vnc://127.0.0.1:5901"},
+		"  open  -a  Viewer  vnc://{} ": {"open", "-a", "Viewer", "vnc://127.0.0.1:5901"},
+	}
+
+	for viewer, expected := range cases {
+		args, err := vncViewerArgs(viewer, "127.0.0.1:5901")
+		if err != nil || !reflect.DeepEqual(args, expected) {
+			t.Errorf("Expected %v for %q, got %v (%v)", expected, viewer, args, err)
+		}
+	}
+
+	_, err := vncViewerArgs("  ", "127.0.0.1:5901")
+	if err == nil {
+		t.Errorf("Expected an error for an empty viewer")
+	}
+}
+
+func TestServeVMISubresource(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			err = conn.WriteMessage(messageType, []byte(strings.ToUpper(string(data))))
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening on a local port: %v", err)
+	}
+	defer listener.Close()
+
+	go serveVMISubresource(listener, "vnc", func() (*websocket.Conn, error) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		return conn, err
+	})
+
+	for i := 0; i < 2; i++ {
+		tcpConn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Error connecting to the local port: %v", err)
+		}
+
+		_, err = tcpConn.Write([]byte("rfb 003.008\n"))
+		if err != nil {
+			t.Fatalf("Error writing to the local port: %v", err)
+		}
+
+		response := make([]byte, len("RFB 003.008\n"))
+		_, err = io.ReadFull(tcpConn, response)
+		if err != nil || string(response) != "RFB 003.008\n" {
+			t.Errorf("Expected the data to be forwarded to the subresource and back, got %q (%v)", response, err)
+		}
+		tcpConn.Close()
+	}
+}
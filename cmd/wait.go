@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	vmWaitRunning        = "running"
+	vmWaitStopped        = "stopped"
+	vmWaitIP             = "ip"
+	vmWaitAgentConnected = "agent-connected"
+	vmWaitDeleted        = "deleted"
+)
+
+var vmWaitConditions = []string{vmWaitRunning, vmWaitStopped, vmWaitIP, vmWaitAgentConnected, vmWaitDeleted}
+
+// vmWaitCommand defines the CLI sub-command `vm wait` that waits for VMs to reach a condition
+func vmWaitCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "wait",
+		Usage:       "Wait for VMs to reach a condition",
+		Description: "\nWaits until each VM given as argument reaches the condition, or fails when the timeout expires. The condition ip waits for an IP address on the network used by `harvester shell`",
+		ArgsUsage:   "[VM_NAME...]",
+		Action:      vmWait,
+		Flags: []cli.Flag{
+			&nsFlag,
+			&cli.StringFlag{
+				Name:     "for",
+				Usage:    "Condition to wait for: " + strings.Join(vmWaitConditions, ", "),
+				Required: true,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Maximum duration to wait for each VM",
+				Value: defaultVMWaitTimeout,
+			},
+		},
+	}
+}
+
+// vmWait implements the `vm wait` command
+func vmWait(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("at least one VM name is required")
+	}
+
+	condition := ctx.String("for")
+	if !isVMWaitCondition(condition) {
+		return fmt.Errorf("invalid condition %s, must be one of %s", condition, strings.Join(vmWaitConditions, ", "))
+	}
+
+	c, err := GetHarvesterClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, vmName := range ctx.Args().Slice() {
+		err = waitForVMCondition(c, ctx.String("namespace"), vmName, condition, ctx.Duration("timeout"))
+		if err != nil {
+			return err
+		}
+		logrus.Infof("VM %s is %s", vmName, condition)
+	}
+
+	return nil
+}
+
+// isVMWaitCondition returns true if condition is a condition known by `vm wait`
+func isVMWaitCondition(condition string) bool {
+	for _, known := range vmWaitConditions {
+		if condition == known {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForVMCondition watches a VM and its VMI until the condition is met
+// the VM watch starts from the version of the VM which was read, and the VMI watch with its current state, so a condition which is already met returns immediately
+func waitForVMCondition(c *harvclient.Clientset, namespace string, name string, condition string, timeout time.Duration) error {
+	vm, err := c.KubevirtV1().VirtualMachines(namespace).Get(context.TODO(), name, k8smetav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if condition == vmWaitDeleted {
+			return nil
+		}
+		return fmt.Errorf("no VM with the provided name %s found: %w", name, err)
+	}
+	if err != nil {
+		return fmt.Errorf("error during fetching of VM %s: %w", name, err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	listOptions := k8smetav1.ListOptions{FieldSelector: "metadata.name=" + name}
+	watchVM := func(resourceVersion string) (watch.Interface, error) {
+		return c.KubevirtV1().VirtualMachines(namespace).Watch(timeoutCtx, k8smetav1.ListOptions{
+			FieldSelector:   listOptions.FieldSelector,
+			ResourceVersion: resourceVersion,
+		})
+	}
+	watchVMI := func() (watch.Interface, error) {
+		return c.KubevirtV1().VirtualMachineInstances(namespace).Watch(timeoutCtx, listOptions)
+	}
+
+	vmWatcher, err := watchVM(vm.ResourceVersion)
+	if err != nil {
+		return fmt.Errorf("error during watching VM %s: %w", name, err)
+	}
+	defer func() { vmWatcher.Stop() }()
+
+	vmiWatcher, err := watchVMI()
+	if err != nil {
+		return fmt.Errorf("error during watching VMI %s: %w", name, err)
+	}
+	defer func() { vmiWatcher.Stop() }()
+
+	var vmi *VMv1.VirtualMachineInstance
+	if vmConditionMet(condition, vm, vmi) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-timeoutCtx.Done():
+			return fmt.Errorf("timed out after %s waiting for VM %s to be %s", timeout, name, condition)
+		case event, ok := <-vmWatcher.ResultChan():
+			if !ok {
+				// the API server closes watches after a while, the VM is read again as it may have been deleted in the meantime
+				vm, err = c.KubevirtV1().VirtualMachines(namespace).Get(context.TODO(), name, k8smetav1.GetOptions{})
+				if errors.IsNotFound(err) {
+					if condition == vmWaitDeleted {
+						return nil
+					}
+					return fmt.Errorf("VM %s was deleted while waiting for it to be %s", name, condition)
+				}
+				if err != nil {
+					return fmt.Errorf("error during fetching of VM %s: %w", name, err)
+				}
+
+				vmWatcher, err = watchVM(vm.ResourceVersion)
+				if err != nil {
+					return fmt.Errorf("error during watching VM %s: %w", name, err)
+				}
+				break
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				vm, _ = event.Object.(*VMv1.VirtualMachine)
+			case watch.Deleted:
+				vm = nil
+				if condition == vmWaitDeleted {
+					return nil
+				}
+			default:
+				continue
+			}
+		case event, ok := <-vmiWatcher.ResultChan():
+			if !ok {
+				vmiWatcher, err = watchVMI()
+				if err != nil {
+					return fmt.Errorf("error during watching VMI %s: %w", name, err)
+				}
+				continue
+			}
+
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				vmi, _ = event.Object.(*VMv1.VirtualMachineInstance)
+			case watch.Deleted:
+				vmi = nil
+			default:
+				continue
+			}
+		}
+
+		if vmConditionMet(condition, vm, vmi) {
+			return nil
+		}
+	}
+}
+
+// vmConditionMet returns true if a VM and its VMI, which are nil if they do not exist, meet the condition
+// the deleted condition is only met by the deletion event of the VM
+func vmConditionMet(condition string, vm *VMv1.VirtualMachine, vmi *VMv1.VirtualMachineInstance) bool {
+	if vm == nil {
+		return false
+	}
+
+	switch condition {
+	case vmWaitRunning:
+		return vmi != nil && vmi.Status.Phase == VMv1.Running
+	case vmWaitStopped:
+		return vm.Status.PrintableStatus == VMv1.VirtualMachineStatusStopped
+	case vmWaitIP:
+		return vmi != nil && vmi.Status.Phase == VMv1.Running && vmNetworkIP(vm, vmi) != ""
+	case vmWaitAgentConnected:
+		return vmi != nil && guestAgentConnected(vmi)
+	default:
+		return false
+	}
+}
+
+// vmNetworkIP returns the IP address of the VMI on the network selected by vmNetworkType, or an empty string if it has none yet
+func vmNetworkIP(vm *VMv1.VirtualMachine, vmi *VMv1.VirtualMachineInstance) string {
+	_, networkNum, err := vmNetworkType(vm)
+	if err != nil {
+		return ""
+	}
+
+	networkName := vm.Spec.Template.Spec.Networks[networkNum].Name
+	for _, iface := range vmi.Status.Interfaces {
+		if iface.Name == networkName {
+			return iface.IP
+		}
+	}
+	return ""
+}
+
+// waitFlags returns the flags of the commands which can wait for the VMs to reach a condition after changing them
+func waitFlags(condition string) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "wait",
+			Usage: "Wait until the VMs are " + condition,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Maximum duration to wait for each VM to be " + condition,
+			Value: defaultVMWaitTimeout,
+		},
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	harvclient "github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestVMConditionMet(t *testing.T) {
+	podNetwork := VMv1.Network{Name: "default", NetworkSource: VMv1.NetworkSource{Pod: &VMv1.PodNetwork{}}}
+	bridgeNetwork := VMv1.Network{Name: "vlan", NetworkSource: VMv1.NetworkSource{Multus: &VMv1.MultusNetwork{NetworkName: "default/vlan1"}}}
+	vm := newTestVM("vm1", podNetwork, bridgeNetwork)
+
+	runningVMI := &VMv1.VirtualMachineInstance{
+		Status: VMv1.VirtualMachineInstanceStatus{
+			Phase:      VMv1.Running,
+			Interfaces: []VMv1.VirtualMachineInstanceNetworkInterface{{Name: "default", IP: "10.52.0.10"}},
+		},
+	}
+
+	if !vmConditionMet(vmWaitRunning, vm, runningVMI) {
+		t.Errorf("Expected a VM with a Running VMI to be running")
+	}
+	if vmConditionMet(vmWaitRunning, vm, nil) {
+		t.Errorf("Expected a VM without VMI not to be running")
+	}
+	if vmConditionMet(vmWaitIP, vm, runningVMI) {
+		t.Errorf("Expected the ip condition to wait for an address on the bridge network")
+	}
+
+	runningVMI.Status.Interfaces = append(runningVMI.Status.Interfaces, VMv1.VirtualMachineInstanceNetworkInterface{Name: "vlan", IP: "192.168.1.20"})
+	if !vmConditionMet(vmWaitIP, vm, runningVMI) {
+		t.Errorf("Expected the ip condition to be met with an address on the bridge network")
+	}
+
+	if vmConditionMet(vmWaitAgentConnected, vm, runningVMI) {
+		t.Errorf("Expected the agent-connected condition not to be met without condition on the VMI")
+	}
+	runningVMI.Status.Conditions = []VMv1.VirtualMachineInstanceCondition{
+		{Type: VMv1.VirtualMachineInstanceAgentConnected, Status: corev1.ConditionTrue},
+	}
+	if !vmConditionMet(vmWaitAgentConnected, vm, runningVMI) {
+		t.Errorf("Expected the agent-connected condition to be met")
+	}
+
+	vm.Status.PrintableStatus = VMv1.VirtualMachineStatusStopped
+	if !vmConditionMet(vmWaitStopped, vm, nil) {
+		t.Errorf("Expected a Stopped VM to be stopped")
+	}
+	if vmConditionMet(vmWaitDeleted, vm, nil) {
+		t.Errorf("Expected an existing VM not to be deleted")
+	}
+}
+
+// newTestWaitClient creates a Harvester client for a VM which is read once and then deleted, the watches of the VM close after sending vmEvents
+func newTestWaitClient(t *testing.T, vmEvents ...watch.Event) *harvclient.Clientset {
+	vm := newTestVM("vm1")
+	vm.TypeMeta = k8smetav1.TypeMeta{Kind: "VirtualMachine", APIVersion: "kubevirt.io/v1"}
+	vm.ResourceVersion = "42"
+
+	gets := 0
+	return newTestHarvesterClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apis/kubevirt.io/v1/namespaces/default/virtualmachines/vm1":
+			gets++
+			if gets > 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				writeTestJSON(t, w, &k8smetav1.Status{
+					TypeMeta: k8smetav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+					Status:   k8smetav1.StatusFailure,
+					Reason:   k8smetav1.StatusReasonNotFound,
+					Code:     http.StatusNotFound,
+				})
+				return
+			}
+			writeTestJSON(t, w, vm)
+		case r.URL.Path == "/apis/kubevirt.io/v1/namespaces/default/virtualmachines" && r.URL.Query().Get("watch") == "true":
+			if r.URL.Query().Get("resourceVersion") != "42" {
+				t.Errorf("Expected the watch of the VM to start from the version read, got %q", r.URL.Query().Get("resourceVersion"))
+			}
+			for _, event := range vmEvents {
+				writeTestJSON(t, w, &k8smetav1.WatchEvent{Type: string(event.Type), Object: runtime.RawExtension{Object: vm}})
+			}
+		case r.URL.Path == "/apis/kubevirt.io/v1/namespaces/default/virtualmachineinstances" && r.URL.Query().Get("watch") == "true":
+			w.Header().Set("Content-Type", "application/json")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestWaitForVMConditionDeleted(t *testing.T) {
+	c := newTestWaitClient(t, watch.Event{Type: watch.Deleted})
+	err := waitForVMCondition(c, "default", "vm1", vmWaitDeleted, 10*time.Second)
+	if err != nil {
+		t.Errorf("Expected the deletion event to meet the deleted condition, got %v", err)
+	}
+
+	// the VM is deleted while the watch is closed, and is not found when the watch is reopened
+	c = newTestWaitClient(t)
+	err = waitForVMCondition(c, "default", "vm1", vmWaitDeleted, 10*time.Second)
+	if err != nil {
+		t.Errorf("Expected a VM not found after the watch closed to meet the deleted condition, got %v", err)
+	}
+
+	c = newTestWaitClient(t)
+	err = waitForVMCondition(c, "default", "vm1", vmWaitRunning, 10*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "was deleted") {
+		t.Errorf("Expected an error for a VM deleted while waiting for it to be running, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// watchRenderDelay is the time during which changes are gathered before rendering again, so that a burst of changes is rendered once
+const watchRenderDelay = 500 * time.Millisecond
+
+var watchFlag = cli.BoolFlag{
+	Name:    "watch",
+	Aliases: []string{"w"},
+	Usage:   "Keep watching the objects and print the list again when they change",
+}
+
+// watchFunc starts a watch on the objects of a listing
+type watchFunc func(ctx context.Context) (watch.Interface, error)
+
+// renderOnChanges calls render, then calls it again each time one of the watches reports a change, until interrupted or a watch fails
+func renderOnChanges(render func() error, watchFuncs ...watchFunc) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 1)
+	errs := make(chan error, len(watchFuncs))
+	for _, fn := range watchFuncs {
+		go func(fn watchFunc) {
+			errs <- forwardWatchChanges(ctx, fn, changes)
+		}(fn)
+	}
+
+	for first := true; ; first = false {
+		clearScreen(first)
+		err := render()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case err := <-errs:
+			return err
+		case <-changes:
+			time.Sleep(watchRenderDelay)
+			select {
+			case <-changes:
+			default:
+			}
+		}
+	}
+}
+
+// forwardWatchChanges signals each event of a watch on changes, the watch is started again when the API server closes it
+func forwardWatchChanges(ctx context.Context, fn watchFunc, changes chan<- struct{}) error {
+	for {
+		watcher, err := fn(ctx)
+		if err != nil {
+			return fmt.Errorf("error during watching for changes: %w", err)
+		}
+
+		for range watcher.ResultChan() {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+		watcher.Stop()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// clearScreen clears the terminal before rendering a listing, or separates the listings with an empty line if the output is not a terminal
+func clearScreen(first bool) {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print("\033[H\033[2J")
+	} else if !first {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/watch"
+	VMv1 "kubevirt.io/api/core/v1"
+)
+
+func TestRenderOnChanges(t *testing.T) {
+	fakeWatcher := watch.NewFake()
+	renders := 0
+
+	err := renderOnChanges(func() error {
+		renders++
+		if renders == 1 {
+			go fakeWatcher.Add(&VMv1.VirtualMachine{})
+			return nil
+		}
+		return fmt.Errorf("stop")
+	}, func(ctx context.Context) (watch.Interface, error) {
+		return fakeWatcher, nil
+	})
+
+	if err == nil || err.Error() != "stop" || renders != 2 {
+		t.Errorf("Expected the listing to be rendered again after a change, got %d renders (%v)", renders, err)
+	}
+}
@@ -58,6 +58,12 @@ func mainErr() error {
 			EnvVars: []string{"RANCHER_CONFIG"},
 			Value:   path.Join(userHome, ".rancher"),
 		},
+		&cli.StringFlag{
+			Name:    "cli-config",
+			Usage:   "Path to the configuration file of the harvester CLI",
+			EnvVars: []string{"HARVESTER_CLI_CONFIG"},
+			Value:   path.Join(userHome, ".harvester", "cli.yaml"),
+		},
 		// cli.StringFlag{
 		// 	Name:   "loglevel",
 		// 	Usage:  "Defines the log level to be used, possible values are error, info, warn, debug and trace",
@@ -65,16 +71,22 @@ func mainErr() error {
 		// 	Value:  "info",
 		// },
 	}
+	app.Flags = append(app.Flags, cmd.OutputFlags()...)
 	app.Commands = []*cli.Command{
 
 		cmd.LoginCommand(),
 		cmd.ConfigCommand(),
 		cmd.VMCommand(),
 		cmd.ShellCommand(),
+		cmd.ExecCommand(),
+		cmd.SCPCommand(),
+		cmd.SSHConfigCommand(),
+		cmd.EventsCommand(),
 		cmd.TemplateCommand(),
 		cmd.ImageCommand(),
 		cmd.KeypairCommand(),
 		cmd.ImportCommand(),
+		cmd.BackupTargetCommand(),
 		cmd.CompleteCommand(),
 	}
 	app.EnableBashCompletion = true
@@ -92,7 +104,11 @@ var singleAlphaLetterRegxp = regexp.MustCompile("[a-zA-Z]")
 
 func parseArgs(args []string) ([]string, error) {
 	result := []string{}
-	for _, arg := range args {
+	for i, arg := range args {
+		if arg == "--" {
+			// arguments after -- are passed as is, e.g. the command run by `harvester exec`
+			return append(result, args[i:]...), nil
+		}
 		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && len(arg) > 1 {
 			for i, c := range arg[1:] {
 				if string(c) == "=" {